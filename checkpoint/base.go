@@ -0,0 +1,49 @@
+package checkpoint
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+type (
+	StoreType string
+)
+
+// known types
+const (
+	StoreTypeFile  StoreType = "file"
+	StoreTypeMySQL StoreType = "mysql"
+	StoreTypeRedis StoreType = "redis"
+)
+
+// storage of checkpoint, lock must be acquired before saving so concurrent runs can't share a checkpoint
+type Store interface {
+	Lock() error
+	Unlock() error
+	Load(cp *config.CheckPoint) (exists bool, err error)
+	Save(cp *config.CheckPoint, reason error) error
+	Location() string
+	Close()
+}
+
+func NewStore(cfg *config.Config) (Store, error) {
+	switch StoreType(cfg.CheckPoint.Type) {
+	case StoreTypeFile:
+		return newFileStore(cfg.Args.CheckPointPath), nil
+	case StoreTypeMySQL:
+		return newMySQLStore(&cfg.CheckPoint, cfg.Args.DryRunFlag)
+	case StoreTypeRedis:
+		return newRedisStore(&cfg.CheckPoint)
+	}
+
+	return nil, fmt.Errorf("unknown checkpoint store type: %s", cfg.CheckPoint.Type)
+}
+
+// identity of lock owner, used for error message when the lock is held by another run
+func getLockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("pid %d on %s since %s", os.Getpid(), host, time.Now().Format(time.RFC3339))
+}
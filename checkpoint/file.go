@@ -0,0 +1,76 @@
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+const LockFileSuffix = ".lock"
+
+type fileStore struct {
+	path     string
+	lockFile *os.File
+}
+
+func newFileStore(path string) *fileStore {
+	if path == "" {
+		path = config.DefaultCheckPointPath
+	}
+
+	return &fileStore{path: path}
+}
+
+// lock file is created exclusively, it's left behind if the process is killed and must be removed manually
+func (store *fileStore) Lock() error {
+	lockPath := store.path + LockFileSuffix
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		owner, _ := os.ReadFile(lockPath)
+		return fmt.Errorf("checkpoint %s is locked by another run (%s), remove %s if no other run is active", store.path, owner, lockPath)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(getLockOwner()); err != nil {
+		f.Close()
+		os.Remove(lockPath)
+		return err
+	}
+
+	store.lockFile = f
+	return nil
+}
+
+func (store *fileStore) Unlock() error {
+	if store.lockFile == nil {
+		return nil
+	}
+
+	store.lockFile.Close()
+	store.lockFile = nil
+
+	return os.Remove(store.path + LockFileSuffix)
+}
+
+func (store *fileStore) Load(cp *config.CheckPoint) (bool, error) {
+	return cp.Load(store.path)
+}
+
+func (store *fileStore) Save(cp *config.CheckPoint, reason error) error {
+	return cp.Save(store.path, reason)
+}
+
+func (store *fileStore) Location() string {
+	return store.path
+}
+
+func (store *fileStore) Close() {
+	store.Unlock()
+}
@@ -0,0 +1,132 @@
+package checkpoint
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/ridwanadhip/universal-uploader/config"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// mysql error number of a table which doesn't exist
+const mySQLErrNoSuchTable = 1146
+
+// checkpoint is stored as a row in a table, the table is created if not exists. Dry run never saves
+// checkpoint, so the table is not created during dry run
+type mySQLStore struct {
+	cfg      *config.CheckPointStore
+	db       *gorm.DB
+	lockConn *sql.Conn // named lock is bound to a connection, so it's kept until the lock is released
+}
+
+func newMySQLStore(cfg *config.CheckPointStore, dryRun bool) (*mySQLStore, error) {
+	connStr := "%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local"
+	connStr = fmt.Sprintf(connStr, cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+	db, err := gorm.Open(mysql.Open(connStr))
+	if err != nil {
+		return nil, err
+	}
+
+	store := &mySQLStore{cfg: cfg, db: db}
+	if dryRun {
+		return store, nil
+	}
+
+	query := "CREATE TABLE IF NOT EXISTS `%s` (`id` VARCHAR(255) NOT NULL PRIMARY KEY, `data` LONGTEXT NOT NULL, `updated_at` DATETIME NOT NULL)"
+	if err := db.Exec(fmt.Sprintf(query, cfg.DataName)).Error; err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *mySQLStore) Lock() error {
+	sqlDB, err := store.db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	// named lock is limited to 64 characters, so use hash of the key
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(SHA1(?), 0)", store.cfg.Key).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return fmt.Errorf("checkpoint %s is locked by another run", store.Location())
+	}
+
+	store.lockConn = conn
+	return nil
+}
+
+func (store *mySQLStore) Unlock() error {
+	if store.lockConn == nil {
+		return nil
+	}
+
+	_, err := store.lockConn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(SHA1(?))", store.cfg.Key)
+	store.lockConn.Close()
+	store.lockConn = nil
+
+	return err
+}
+
+func (store *mySQLStore) Load(cp *config.CheckPoint) (bool, error) {
+	var data string
+	query := fmt.Sprintf("SELECT `data` FROM `%s` WHERE `id` = ?", store.cfg.DataName)
+
+	// table doesn't exist if checkpoint is never saved, e.g. during dry run
+	var mysqlErr *mysqldriver.MySQLError
+	err := store.db.Raw(query, store.cfg.Key).Row().Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &mysqlErr) && mysqlErr.Number == mySQLErrNoSuchTable) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, cp.Decode([]byte(data))
+}
+
+// single upsert statement, so the existing checkpoint is never partially written. It's executed on the
+// connection which holds the lock, so it fails if the lock is released because the connection is lost
+func (store *mySQLStore) Save(cp *config.CheckPoint, reason error) error {
+	if store.lockConn == nil {
+		return fmt.Errorf("checkpoint %s is not locked by this run", store.Location())
+	}
+
+	query := "INSERT INTO `%s` (`id`, `data`, `updated_at`) VALUES (?, ?, NOW()) ON DUPLICATE KEY UPDATE `data` = VALUES(`data`), `updated_at` = VALUES(`updated_at`)"
+	_, err := store.lockConn.ExecContext(context.Background(), fmt.Sprintf(query, store.cfg.DataName), store.cfg.Key, string(cp.Encode(reason)))
+
+	return err
+}
+
+func (store *mySQLStore) Location() string {
+	return fmt.Sprintf("mysql %s:%d/%s.%s id %s", store.cfg.Host, store.cfg.Port, store.cfg.Name, store.cfg.DataName, store.cfg.Key)
+}
+
+func (store *mySQLStore) Close() {
+	store.Unlock()
+
+	db, _ := store.db.DB()
+	if db != nil {
+		db.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+const (
+	LockKeySuffix   = ":lock"
+	redisLockTTL    = 30 * time.Second
+	redisLockRenew  = 10 * time.Second
+	redisUnlockEval = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+	redisRenewEval  = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+	redisSaveEval   = `if redis.call("GET", KEYS[1]) == ARGV[1] then redis.call("SET", KEYS[2], ARGV[2]) return 1 else return 0 end`
+)
+
+// lock key expires so it doesn't block next run if the process is killed, it's renewed while the lock is held.
+// Lock is renewed and checkpoint is saved only if the lock is still owned by this run, so a run whose lock
+// is expired and taken by another run can't overwrite the checkpoint of that run
+type redisStore struct {
+	cfg       *config.CheckPointStore
+	client    *redis.Client
+	lockOwner string
+	stopRenew chan struct{}
+}
+
+func newRedisStore(cfg *config.CheckPointStore) (*redisStore, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Username: cfg.Username,
+		Password: cfg.Password,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &redisStore{cfg: cfg, client: client}, nil
+}
+
+func (store *redisStore) Lock() error {
+	ctx := context.Background()
+	lockKey := store.cfg.Key + LockKeySuffix
+	owner := getLockOwner()
+
+	acquired, err := store.client.SetNX(ctx, lockKey, owner, redisLockTTL).Result()
+	if err != nil {
+		return err
+	}
+
+	if !acquired {
+		current, _ := store.client.Get(ctx, lockKey).Result()
+		return fmt.Errorf("checkpoint %s is locked by another run (%s)", store.Location(), current)
+	}
+
+	store.lockOwner = owner
+	store.stopRenew = make(chan struct{})
+	go store.renewLock(store.stopRenew)
+
+	return nil
+}
+
+func (store *redisStore) Unlock() error {
+	if store.lockOwner == "" {
+		return nil
+	}
+
+	close(store.stopRenew)
+
+	lockKey := store.cfg.Key + LockKeySuffix
+	err := store.client.Eval(context.Background(), redisUnlockEval, []string{lockKey}, store.lockOwner).Err()
+	store.lockOwner = ""
+
+	return err
+}
+
+func (store *redisStore) Load(cp *config.CheckPoint) (bool, error) {
+	data, err := store.client.Get(context.Background(), store.cfg.Key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, cp.Decode(data)
+}
+
+// single SET command, so the existing checkpoint is never partially written
+func (store *redisStore) Save(cp *config.CheckPoint, reason error) error {
+	if store.lockOwner == "" {
+		return fmt.Errorf("checkpoint %s is not locked by this run", store.Location())
+	}
+
+	keys := []string{store.cfg.Key + LockKeySuffix, store.cfg.Key}
+	saved, err := store.client.Eval(context.Background(), redisSaveEval, keys, store.lockOwner, cp.Encode(reason)).Int()
+	if err != nil {
+		return err
+	}
+
+	if saved == 0 {
+		return fmt.Errorf("lock of checkpoint %s is lost, it's expired or held by another run", store.Location())
+	}
+
+	return nil
+}
+
+func (store *redisStore) Location() string {
+	return fmt.Sprintf("redis %s:%d key %s", store.cfg.Host, store.cfg.Port, store.cfg.Key)
+}
+
+func (store *redisStore) Close() {
+	store.Unlock()
+	store.client.Close()
+}
+
+func (store *redisStore) renewLock(stop chan struct{}) {
+	ticker := time.NewTicker(redisLockRenew)
+	defer ticker.Stop()
+
+	lockKey := store.cfg.Key + LockKeySuffix
+	owner := store.lockOwner
+	for {
+		select {
+		case <-ticker.C:
+			renewed, err := store.client.Eval(context.Background(), redisRenewEval, []string{lockKey}, owner, redisLockTTL.Milliseconds()).Int()
+			if err != nil {
+				fmt.Printf("[Check Point] unable to renew lock of %s: %s\n", store.Location(), err)
+				continue
+			}
+
+			// saving checkpoint fails from now on
+			if renewed == 0 {
+				fmt.Printf("[Check Point] lock of %s is lost, it's expired or held by another run\n", store.Location())
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
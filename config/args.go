@@ -3,6 +3,9 @@ package config
 import (
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -11,6 +14,7 @@ type Args struct {
 	DryRunFlag      bool
 	VerboseModeFlag bool
 	ResumeFlag      bool
+	ForceResumeFlag bool
 	ConfigType      string
 	ConfigPath      string
 	InputPath       string
@@ -22,18 +26,83 @@ func (args *Args) Parse() error {
 	flag.BoolVar(&args.DryRunFlag, "dry-run", false, "")
 	flag.BoolVar(&args.VerboseModeFlag, "verbose", false, "")
 	flag.BoolVar(&args.ResumeFlag, "resume", false, "")
+	flag.BoolVar(&args.ForceResumeFlag, "force-resume", false, "")
 	flag.StringVar(&args.ConfigType, "config-type", string(ConfigTypeYAML), "")
 	flag.StringVar(&args.CheckPointPath, "check-point-path", DefaultCheckPointPath, "")
 
 	flag.Parse()
 	tailArgs := flag.Args()
 
-	if len(tailArgs) != 2 {
-		return fmt.Errorf("require config and input file path")
+	// input file path is not needed by input which is read from a server
+	if len(tailArgs) != 1 && len(tailArgs) != 2 {
+		return fmt.Errorf("require config and input file path, use - to read input from stdin")
 	}
 
 	args.ConfigPath = strings.TrimSpace(tailArgs[0])
-	args.InputPath = strings.TrimSpace(tailArgs[1])
+	if len(tailArgs) == 2 {
+		args.InputPath = strings.TrimSpace(tailArgs[1])
+	}
 
 	return nil
 }
+
+// false if input is read from stdin or from a source given by library user
+func (args *Args) HasInputFile() bool {
+	return args.InputPath != "" && args.InputPath != StdinPath
+}
+
+// true if input path is a glob pattern or a directory, so it may match more than one input file
+func (args *Args) HasInputPattern() bool {
+	if !args.HasInputFile() {
+		return false
+	}
+
+	info, err := os.Stat(args.InputPath)
+	if err == nil {
+		return info.IsDir()
+	}
+
+	return strings.ContainsAny(args.InputPath, "*?[")
+}
+
+// input files matched by input path in lexical order, so zero padded names such as part-0001.csv are read
+// in sequence. Sub directories and hidden files are ignored
+func (args *Args) GetInputFiles() ([]string, error) {
+	if !args.HasInputPattern() {
+		return []string{args.InputPath}, nil
+	}
+
+	pattern := args.InputPath
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = filepath.Join(pattern, "*")
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input path pattern %s: %w", args.InputPath, err)
+	}
+
+	res := []string{}
+	for _, path := range matches {
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode().IsRegular() {
+			res = append(res, path)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no input file matches %s", args.InputPath)
+	}
+
+	sort.Strings(res)
+
+	return res, nil
+}
@@ -9,6 +9,11 @@ type (
 	TargetType string
 	ConfigType string
 	TargetMode string
+
+	RaggedRowPolicy string
+	GeneratorType   string
+	JoinType        string
+	JoinIndexType   string
 )
 
 const (
@@ -29,6 +34,29 @@ const (
 	TargetModeInsert TargetMode = "insert"
 	TargetModeUpsert TargetMode = "upsert"
 	TargetModeUpdate TargetMode = "update"
+
+	// handling of input rows which total columns is different from total fields of input file
+	RaggedRowError    RaggedRowPolicy = "error"
+	RaggedRowSkip     RaggedRowPolicy = "skip"     // skip and write it to failed rows file
+	RaggedRowPad      RaggedRowPolicy = "pad"      // only for short rows, missing columns are empty
+	RaggedRowTruncate RaggedRowPolicy = "truncate" // only for long rows, extra columns are ignored
+
+	// rules of generated input value
+	GeneratorSequence GeneratorType = "sequence"
+	GeneratorInt      GeneratorType = "int"
+	GeneratorDecimal  GeneratorType = "decimal"
+	GeneratorUUID     GeneratorType = "uuid"
+	GeneratorPick     GeneratorType = "pick"
+	GeneratorDate     GeneratorType = "date"
+	GeneratorPattern  GeneratorType = "pattern"
+
+	// handling of input row which doesn't have matching row in secondary input
+	JoinTypeInner JoinType = "inner" // skip and write it to failed rows file
+	JoinTypeLeft  JoinType = "left"  // keep it, columns of secondary input are null
+
+	// storage of secondary input rows
+	JoinIndexMemory JoinIndexType = "memory"
+	JoinIndexDisk   JoinIndexType = "disk" // rows are written to temporary file, only hash of keys is kept in memory
 )
 
 // constants
@@ -43,13 +71,41 @@ const (
 	DefaultReferenceToken = '^'
 	DefaultBatchSize      = 250
 	DefaultDelay          = 1000
+	DefaultHeaderRow      = 1
+	DefaultDelimiter      = ','
+	DefaultJoinType       = JoinTypeInner
+	DefaultJoinIndex      = JoinIndexMemory
+
+	// retry policy, durations are in ms
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 500
+	DefaultRetryMaxBackoff     = 30000
+	DefaultRetryMultiplier     = 2.0
+	DefaultRetryJitter         = 0.2
 
 	// ports
 	DefaultMySQLPort = 3306
 	DefaultRedisPort = 6379
 
+	// checkpoint store
+	DefaultCheckPointStoreType = "file"
+	DefaultCheckPointTable     = "universal_uploader_checkpoint"
+	DefaultCheckPointKeyPrefix = "universal-uploader:checkpoint"
+
 	// paths
 	DefaultCheckPointPath = ".checkpoint"
+	DefaultOutputPath     = "result" // file extension is appended based on output type
+	DefaultFailedRowsPath = "failed" // suffix of input file name, e.g. data.csv become data.failed.csv
+	StdinPath             = "-"      // input path which means input is read from stdin
+
+	// extra column of failed rows file, ignored when the file is uploaded again
+	FailedRowsErrorColumn = "upload_error"
+
+	// separator of join alias and column of secondary input, e.g. codes.new_code
+	JoinAliasSeparator = "."
+
+	// placeholder for credentials in exported config
+	RedactedValue = "******"
 )
 
 // map and list constants
@@ -60,6 +116,70 @@ var (
 		ValueTypeBoolean: true,
 		ValueTypeDecimal: true,
 	}
+
+	validShortRowPolicy = map[RaggedRowPolicy]bool{
+		RaggedRowError: true,
+		RaggedRowSkip:  true,
+		RaggedRowPad:   true,
+	}
+
+	validLongRowPolicy = map[RaggedRowPolicy]bool{
+		RaggedRowError:    true,
+		RaggedRowSkip:     true,
+		RaggedRowTruncate: true,
+	}
+
+	validJoinType = map[JoinType]bool{
+		JoinTypeInner: true,
+		JoinTypeLeft:  true,
+	}
+
+	validJoinIndex = map[JoinIndexType]bool{
+		JoinIndexMemory: true,
+		JoinIndexDisk:   true,
+	}
+
+	// extensions of compressed input file
+	compressedFileExtensions = map[string]bool{
+		".gz":   true,
+		".gzip": true,
+		".zst":  true,
+		".zstd": true,
+		".zip":  true,
+	}
+
+	// input types which are not read from input file
+	nonFileInputTypes = map[string]bool{
+		string(TargetTypeMySQL): true,
+		string(TargetTypeRedis): true,
+		"generate":              true,
+	}
+
+	// input types which are read from a server
+	serverInputTypes = map[string]bool{
+		string(TargetTypeMySQL): true,
+		string(TargetTypeRedis): true,
+	}
+
+	// input types which failed rows are written as csv, because they are not read from file or their
+	// format can't be written from text values
+	csvFailedRowsInputTypes = map[string]bool{
+		string(TargetTypeMySQL): true,
+		string(TargetTypeRedis): true,
+		"parquet":               true,
+		"xml":                   true,
+		"generate":              true,
+	}
+
+	// names of input delimiter and comment character
+	namedInputRunes = map[string]rune{
+		"tab":       '\t',
+		"\\t":       '\t',
+		"comma":     ',',
+		"semicolon": ';',
+		"pipe":      '|',
+		"space":     ' ',
+	}
 )
 
 func (val ValueType) Validate() error {
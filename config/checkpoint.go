@@ -3,36 +3,66 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/ridwanadhip/universal-uploader/util"
 )
 
 type CheckPoint struct {
-	ConfigFile string
-	InputFile  string
-	Timestamp  time.Time
-	Error      string
-	BatchSize  int
-	Progress   map[string]int // map of target id to last executed data index, 0 based index
+	ConfigFile      string
+	InputFile       string
+	InputHash       string
+	InputConfigHash string // fingerprint of input config, see Input.Fingerprint
+
+	TargetHashes map[string]string // map of target id to fingerprint of target definition
+	Timestamp    time.Time
+	Error        string
+	BatchSize    int
+	Progress     map[string]int           // map of target id to total of processed rows, also 0 based index of the next row
+	Positions    map[string]InputPosition // map of target id to position of the next row in input file
+}
+
+// position of a row in input file, used for seeking input file directly when resumed. If input path
+// matches more than one file then the file of the row is recorded, so reading continues from that file
+type InputPosition struct {
+	Row     int    // 0 based index of data row
+	Offset  int64  // byte offset in input file, -1 if unknown
+	File    string `json:",omitempty"`
+	FileRow int    `json:",omitempty"` // 0 based index of data row in its file
 }
 
+// write to temporary file then rename it, so the existing checkpoint is never partially written
 func (cp *CheckPoint) Save(path string, reason error) error {
-	cp.Timestamp = time.Now()
-	cp.Error = reason.Error()
+	if path == "" {
+		path = DefaultCheckPointPath
+	}
 
-	f, err := os.OpenFile(path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return err
 	}
 
-	defer f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(cp.Encode(reason))
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 
-	newData := util.Jsonify(cp)
-	_, err = f.WriteString(newData)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
 }
 
 func (cp *CheckPoint) Load(path string) (exists bool, err error) {
@@ -49,7 +79,7 @@ func (cp *CheckPoint) Load(path string) (exists bool, err error) {
 		return false, err
 	}
 
-	err = json.Unmarshal(content, cp)
+	err = cp.Decode(content)
 	if err != nil {
 		return false, err
 	}
@@ -57,6 +87,84 @@ func (cp *CheckPoint) Load(path string) (exists bool, err error) {
 	return true, nil
 }
 
+// mark saving time and reason, then serialize the checkpoint
+func (cp *CheckPoint) Encode(reason error) []byte {
+	cp.Timestamp = time.Now()
+	cp.Error = reason.Error()
+
+	return []byte(util.Jsonify(cp))
+}
+
+func (cp *CheckPoint) Decode(data []byte) error {
+	return json.Unmarshal(data, cp)
+}
+
+// compare fingerprints of saved checkpoint with current one, changed batch size is allowed because progress
+// is recorded as total of rows. Checkpoint from older version without fingerprint is always accepted
+func (cp *CheckPoint) Verify(current *CheckPoint) error {
+	mismatches := []string{}
+
+	if cp.InputHash != "" && cp.InputHash != current.InputHash {
+		mismatches = append(mismatches, fmt.Sprintf("input file %s has been modified", current.InputFile))
+	}
+
+	if cp.InputConfigHash != "" && cp.InputConfigHash != current.InputConfigHash {
+		mismatches = append(mismatches, "input config has been changed")
+	}
+
+	for id, hash := range cp.TargetHashes {
+		currentHash, exists := current.TargetHashes[id]
+		if !exists {
+			mismatches = append(mismatches, fmt.Sprintf("target %s has been removed", id))
+		} else if hash != currentHash {
+			mismatches = append(mismatches, fmt.Sprintf("target %s has been changed", id))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("checkpoint does not match current run: %s", strings.Join(mismatches, ", "))
+	}
+
+	return nil
+}
+
+// continue progress of saved checkpoint, fingerprints of current run are kept
+func (cp *CheckPoint) Resume(saved *CheckPoint) {
+	cp.Timestamp = saved.Timestamp
+	cp.Error = saved.Error
+
+	for id := range cp.Progress {
+		cp.Progress[id] = saved.Progress[id]
+
+		if pos, exists := saved.Positions[id]; exists && pos.Row == saved.Progress[id] {
+			cp.Positions[id] = pos
+		}
+	}
+}
+
+func (cp *CheckPoint) SetProgress(targetID string, pos InputPosition) {
+	cp.Progress[targetID] = pos.Row
+	cp.Positions[targetID] = pos
+}
+
+// the earliest position of every target, which is the position to continue reading input file.
+// Returns false if the position of any target is unknown
+func (cp *CheckPoint) GetResumePosition() (res InputPosition, exists bool) {
+	for id := range cp.Progress {
+		pos, ok := cp.Positions[id]
+		if !ok {
+			return InputPosition{}, false
+		}
+
+		if !exists || pos.Row < res.Row {
+			res = pos
+			exists = true
+		}
+	}
+
+	return res, exists
+}
+
 func (cp *CheckPoint) IsLoaded() bool {
 	return !cp.Timestamp.IsZero() || cp.Error != ""
 }
@@ -2,17 +2,23 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/ridwanadhip/universal-uploader/util"
 )
 
 type Config struct {
-	Args      *Args        `yaml:"-"`
-	Parser    ParserConfig `yaml:"-"`
-	Input     Input
-	Output    Output
-	Targets   []Target
-	TargetMap map[string]*Target `yaml:"-"`
+	Args       *Args        `yaml:"-"`
+	Parser     ParserConfig `yaml:"-"`
+	Input      Input
+	Output     Output
+	FailedRows FailedRows      `yaml:"failedRows"`
+	CheckPoint CheckPointStore `yaml:"checkPoint"`
+	Targets    []Target
+	TargetMap  map[string]*Target `yaml:"-"`
 
 	// general configurations
 	BatchSize int `yaml:"batchSize"`
@@ -31,11 +37,47 @@ type ParserConfig struct {
 type Input struct {
 	Type            string
 	Fields          []InputField
+	Files           []string               `yaml:"-"` // input files matched by input path, read as one stream
 	TrimSpaces      bool                   `yaml:"trimSpaces"`
+	Encoding        string                 // e.g. windows-1252 or utf-16le, detected from byte order mark if exists
+	Compression     string                 // auto, none, gzip, zstd, or zip
+	Entry           string                 // name or glob pattern of input file inside zip archive
+	Sheet           string                 // xlsx sheet name or 1 based index, default is the first sheet
+	HeaderRow       int                    `yaml:"headerRow"` // 1 based row number of header after skipped lines
+	RawValues       bool                   `yaml:"rawValues"` // read raw xlsx cell values instead of displayed text
+	HasHeader       *bool                  `yaml:"hasHeader"` // if false then csv columns are mapped by field order only
+	LazyQuotes      bool                   `yaml:"lazyQuotes"`
+	SkipLines       int                    `yaml:"skipLines"`         // total of csv lines skipped before header
+	SkipTrailing    int                    `yaml:"skipTrailingLines"` // total of csv rows skipped at the end of file
+	ShortRows       RaggedRowPolicy        `yaml:"shortRows"`         // error, skip, or pad
+	LongRows        RaggedRowPolicy        `yaml:"longRows"`          // error, skip, or truncate
+	RecordType      *RecordType            `yaml:"recordType"`        // only read fixed width lines of this record type
+	RecordPath      string                 `yaml:"recordPath"`        // xml elements which are read as rows, e.g. /Orders/Order
+	Rows            int                    // total rows of generate input
+	Seed            *int64                 // seed of generate input, so generated rows are reproducible
+	Joins           []Join                 // secondary inputs which are joined to every row by key
+	Delimiter       rune                   `yaml:"-"`
+	Comment         rune                   `yaml:"-"`
 	InjectFields    bool                   `yaml:"-"`
 	FieldsIDMap     map[string]*InputField `yaml:"-"`
 	FieldsNameIDMap map[string]string      `yaml:"-"`
 	FieldsIndexMap  map[string]int         `yaml:"-"`
+
+	// connection of input which is read from a server, name is the database name or redis database number
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	Name       string
+	Query      string   // select query of mysql input
+	Key        string   // unique column of query result, rows are read in pages ordered by this column
+	Pattern    string   // redis keys which are scanned, default is all keys
+	KeyType    string   `yaml:"keyType"`    // only scan redis keys of this type, e.g. string or hash
+	HashFields []string `yaml:"hashFields"` // fields of redis hash which are read as columns
+
+	// unparsed data
+	DelimiterRaw string `yaml:"delimiter"`
+	CommentRaw   string `yaml:"comment"`
 }
 
 type InputField struct {
@@ -43,6 +85,46 @@ type InputField struct {
 	Name       string
 	Order      *int
 	TrimSpaces bool `yaml:"trimSpaces"`
+	Start      int  // 1 based position of the first character in fixed width line
+	Width      int
+	End        int        // 1 based position of the last character, used if width is not defined
+	Generator  *Generator // rule of generated value, only for generate input
+}
+
+// record type of fixed width line is the text at its position, lines of other record types such as
+// header and trailer are skipped
+type RecordType struct {
+	Start  int
+	Width  int
+	End    int
+	Values []string
+}
+
+// secondary input which is joined to input rows by key, its columns are referenced by alias and column
+// name, e.g. ^codes.new_code^. Key must be unique in secondary input
+type Join struct {
+	Alias  string
+	Path   string        // path of secondary input file, it can be a glob pattern or a directory
+	Key    string        // field of secondary input which is matched
+	On     string        // field of input which is matched with key, default is the key
+	Type   JoinType      // inner or left, default is inner
+	Index  JoinIndexType // memory or disk, default is memory
+	Input  Input         // type and format of secondary input, e.g. type, delimiter, and encoding
+	Config *Config       `yaml:"-" json:"-"` // config of secondary input, so it's read like input of its own config
+}
+
+// rule of generated input value. Min and max are numbers for int and decimal generator, and dates in the
+// generator format for date generator, both are inclusive
+type Generator struct {
+	Type    GeneratorType
+	Start   int64 // first value of sequence
+	Step    int64 // increment of sequence, default is 1
+	Min     string
+	Max     string
+	Scale   int      // digits after decimal point of decimal generator
+	Values  []string // values which are picked randomly by pick generator
+	Format  string   // layout of date generator, e.g. 2006-01-02, or fmt layout of sequence, e.g. INV%06d
+	Pattern string   // regular expression which generated value matches, e.g. [A-Z]{3}-\d{4}
 }
 
 type Target struct {
@@ -56,7 +138,11 @@ type Target struct {
 	Password          string
 	Upsert            bool
 	Mode              TargetMode
+	IsolateRows       bool   `yaml:"isolateRows"` // if true then execute failed batch row by row, so only bad rows are rejected
+	MaxErrors         string `yaml:"maxErrors"`   // total of failed rows before the run is stopped, can be a number or percentage, e.g. 10 or 5%
+	Retry             Retry
 	Fields            []TargetField
+	ErrorBudget       ErrorBudget             `yaml:"-"`
 	InjectFields      bool                    `yaml:"-"`
 	FieldsIDMap       map[string]*TargetField `yaml:"-"`
 	FieldsNameIDMap   map[string]string       `yaml:"-"`
@@ -78,9 +164,45 @@ type TargetField struct {
 	ValueIfEmpty    *string `yaml:"valueIfEmpty"`
 }
 
+// retry policy for transient errors, backoff durations are in ms
+type Retry struct {
+	MaxAttempts    int      `yaml:"maxAttempts"`
+	InitialBackoff *int     `yaml:"initialBackoff"`
+	MaxBackoff     *int     `yaml:"maxBackoff"`
+	Multiplier     float64  // backoff multiplier after each attempt
+	Jitter         *float64 // random fraction of backoff added or subtracted, between 0 and 1
+}
+
+// parsed value of target max errors, only one of the value is used
+type ErrorBudget struct {
+	Count      int
+	Percentage float64
+	IsRelative bool
+}
+
 type Output struct {
 	Type   string
 	Enable bool
+	Path   string
+}
+
+// rejected input rows written in the original input format, so they can be fixed and uploaded again
+type FailedRows struct {
+	Enable bool
+	Path   string
+}
+
+// storage of checkpoint, file path is defined by --check-point-path argument. Database and table name
+// are used by mysql store, while key is used as row id in mysql store and as key in redis store
+type CheckPointStore struct {
+	Type     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Name     string
+	DataName string `yaml:"dataName"`
+	Key      string
 }
 
 // TODO: research library: https://github.com/creasty/defaults
@@ -100,11 +222,26 @@ func (cfg *Config) SetDefaults() error {
 		return err
 	}
 
+	err = cfg.setJoinDefaults()
+	if err != nil {
+		return err
+	}
+
 	err = cfg.setOutputDefaults()
 	if err != nil {
 		return err
 	}
 
+	err = cfg.setFailedRowsDefaults()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.setCheckPointDefaults()
+	if err != nil {
+		return err
+	}
+
 	err = cfg.setTargetDefaults()
 	if err != nil {
 		return err
@@ -124,6 +261,11 @@ func (cfg *Config) InjectFieldsWithDefaultValue(fieldNames []string) {
 		injected = true
 
 		for _, f := range fieldNames {
+			// skip error column from previous failed rows file
+			if f == FailedRowsErrorColumn {
+				continue
+			}
+
 			cfg.Input.Fields = append(cfg.Input.Fields, InputField{
 				ID:   f,
 				Name: f,
@@ -160,19 +302,201 @@ func (cfg *Config) InjectFieldsWithDefaultValue(fieldNames []string) {
 	}
 }
 
-func (cfg *Config) NewCheckPoint() *CheckPoint {
+// columns of secondary inputs which are referenced by target fields are added as input fields, order
+// of the first column is the total columns of input file. Injected target fields don't include them
+func (cfg *Config) InjectJoinFields(joinedNames []string, offset int) {
+	referenced := map[string]bool{}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		for j := range t.Fields {
+			for _, ref := range t.Fields[j].References {
+				referenced[util.RemoveToken(ref)] = true
+			}
+		}
+	}
+
+	injected := false
+	for i, name := range joinedNames {
+		if !referenced[name] {
+			continue
+		}
+
+		if _, exists := cfg.Input.FieldsIDMap[name]; exists {
+			continue
+		}
+
+		order := offset + i
+		cfg.Input.Fields = append(cfg.Input.Fields, InputField{
+			ID:    name,
+			Name:  name,
+			Order: &order,
+		})
+
+		injected = true
+	}
+
+	if injected {
+		cfg.constructHelperMaps()
+	}
+}
+
+func (cfg *Config) NewCheckPoint() (*CheckPoint, error) {
 	cp := &CheckPoint{
-		ConfigFile: cfg.Args.ConfigPath,
-		InputFile:  cfg.Args.InputPath,
-		BatchSize:  cfg.BatchSize,
-		Progress:   map[string]int{},
+		ConfigFile:   cfg.Args.ConfigPath,
+		InputFile:    cfg.Args.InputPath,
+		TargetHashes: map[string]string{},
+		BatchSize:    cfg.BatchSize,
+		Progress:     map[string]int{},
+		Positions:    map[string]InputPosition{},
+	}
+
+	// config file itself is not fingerprinted, because changing batch size, credentials or retry policy is
+	// safe when resuming. Only input config, targets and input files are verified
+	cp.InputConfigHash = cfg.Input.Fingerprint()
+
+	// stream can't be fingerprinted, so it's not verified when resumed
+	if cfg.Args.HasInputPattern() {
+		inputHash, err := util.FingerprintFiles(cfg.Input.Files)
+		if err != nil {
+			return nil, err
+		}
+
+		cp.InputHash = inputHash
+	} else if cfg.Args.HasInputFile() {
+		inputHash, err := util.FingerprintFile(cfg.Args.InputPath)
+		if err != nil {
+			return nil, err
+		}
+
+		cp.InputHash = inputHash
+	}
+
+	// secondary inputs determine the joined values, so they are verified together with input file
+	joinFiles := []string{}
+	for i := range cfg.Input.Joins {
+		joinFiles = append(joinFiles, cfg.Input.Joins[i].Config.Input.Files...)
+	}
+
+	if len(joinFiles) > 0 {
+		joinHash, err := util.FingerprintFiles(joinFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		cp.InputHash = util.HashString(cp.InputHash + joinHash)
 	}
 
 	for i := range cfg.Targets {
-		cp.Progress[cfg.Targets[i].ID] = 0
+		t := &cfg.Targets[i]
+		cp.TargetHashes[t.ID] = t.Fingerprint()
+		cp.Progress[t.ID] = 0
+	}
+
+	return cp, nil
+}
+
+// hash of input properties which determine row numbering or values, changing credentials is safe when
+// resuming. Input files are fingerprinted separately
+func (input *Input) Fingerprint() string {
+	joins := []string{}
+	for i := range input.Joins {
+		join := &input.Joins[i]
+		joins = append(joins, util.Jsonify([]string{join.Alias, join.Path, join.Key, join.On, string(join.Type), string(join.Index), join.Input.Fingerprint()}))
+	}
+
+	definition := struct {
+		Type         string
+		Fields       []InputField
+		TrimSpaces   bool
+		Encoding     string
+		Compression  string
+		Entry        string
+		Sheet        string
+		HeaderRow    int
+		RawValues    bool
+		HasHeader    *bool
+		LazyQuotes   bool
+		SkipLines    int
+		SkipTrailing int
+		ShortRows    RaggedRowPolicy
+		LongRows     RaggedRowPolicy
+		RecordType   *RecordType
+		RecordPath   string
+		Rows         int
+		Seed         *int64
+		Joins        []string
+		Delimiter    rune
+		Comment      rune
+		Host         string
+		Port         int
+		Name         string
+		Query        string
+		Key          string
+		Pattern      string
+		KeyType      string
+		HashFields   []string
+	}{
+		input.Type, input.Fields, input.TrimSpaces, input.Encoding, input.Compression, input.Entry, input.Sheet,
+		input.HeaderRow, input.RawValues, input.HasHeader, input.LazyQuotes, input.SkipLines, input.SkipTrailing,
+		input.ShortRows, input.LongRows, input.RecordType, input.RecordPath, input.Rows, input.Seed, joins,
+		input.Delimiter, input.Comment, input.Host, input.Port, input.Name, input.Query, input.Key, input.Pattern,
+		input.KeyType, input.HashFields,
+	}
+
+	return util.HashString(util.Jsonify(definition))
+}
+
+// hash of target properties which determine the uploaded data, changing other properties such as
+// credentials or retry policy is safe when resuming
+func (t *Target) Fingerprint() string {
+	definition := struct {
+		Type     TargetType
+		Name     string
+		DataName string
+		Host     string
+		Port     int
+		Upsert   bool
+		Mode     TargetMode
+		Fields   []TargetField
+	}{t.Type, t.Name, t.DataName, t.Host, t.Port, t.Upsert, t.Mode, t.Fields}
+
+	return util.HashString(util.Jsonify(definition))
+}
+
+// copy of config with masked credentials, safe to be exported into a file
+func (cfg *Config) Redacted() *Config {
+	res := *cfg
+	res.TargetMap = nil
+	res.Targets = make([]Target, len(cfg.Targets))
+
+	for i := range cfg.Targets {
+		t := cfg.Targets[i]
+		if t.Password != "" {
+			t.Password = RedactedValue
+		}
+
+		res.Targets[i] = t
+	}
+
+	if res.Input.Password != "" {
+		res.Input.Password = RedactedValue
+	}
+
+	res.Input.Joins = make([]Join, len(cfg.Input.Joins))
+	for i := range cfg.Input.Joins {
+		join := cfg.Input.Joins[i]
+		if join.Input.Password != "" {
+			join.Input.Password = RedactedValue
+		}
+
+		res.Input.Joins[i] = join
+	}
+
+	if res.CheckPoint.Password != "" {
+		res.CheckPoint.Password = RedactedValue
 	}
 
-	return cp
+	return &res
 }
 
 func (cfg *Config) setDefaultValues() error {
@@ -217,6 +541,77 @@ func (cfg *Config) setInputDefaults() error {
 		cfg.Input.Type = DefaultInputType
 	}
 
+	if cfg.Input.HeaderRow == 0 {
+		cfg.Input.HeaderRow = DefaultHeaderRow
+	}
+
+	if cfg.Input.HeaderRow < 1 {
+		return fmt.Errorf("input header row must be greater than 0")
+	}
+
+	if !cfg.Input.IsFile() && cfg.Args.InputPath != "" {
+		return fmt.Errorf("%s input doesn't read input file, remove input file path from arguments", cfg.Input.Type)
+	}
+
+	if serverInputTypes[cfg.Input.Type] && cfg.Input.Host == "" {
+		cfg.Input.Host = DefaultHost
+	}
+
+	if serverInputTypes[cfg.Input.Type] && cfg.Input.Port == 0 {
+		cfg.Input.Port = getDefaultPort(TargetType(cfg.Input.Type))
+	}
+
+	if cfg.Args.HasInputFile() {
+		files, err := cfg.Args.GetInputFiles()
+		if err != nil {
+			return err
+		}
+
+		cfg.Input.Files = files
+	}
+
+	if cfg.Input.HasHeader == nil {
+		hasHeader := true
+		cfg.Input.HasHeader = &hasHeader
+	}
+
+	if cfg.Input.SkipLines < 0 || cfg.Input.SkipTrailing < 0 {
+		return fmt.Errorf("total of skipped input lines must not be negative")
+	}
+
+	delimiter, err := parseInputRune(cfg.Input.DelimiterRaw, DefaultDelimiter)
+	if err != nil {
+		return fmt.Errorf("invalid input delimiter: %s", err)
+	}
+
+	comment, err := parseInputRune(cfg.Input.CommentRaw, 0)
+	if err != nil {
+		return fmt.Errorf("invalid input comment character: %s", err)
+	}
+
+	if delimiter == comment {
+		return fmt.Errorf("input delimiter and comment character must be different")
+	}
+
+	cfg.Input.Delimiter = delimiter
+	cfg.Input.Comment = comment
+
+	if cfg.Input.ShortRows == "" {
+		cfg.Input.ShortRows = RaggedRowError
+	}
+
+	if cfg.Input.LongRows == "" {
+		cfg.Input.LongRows = RaggedRowError
+	}
+
+	if !validShortRowPolicy[cfg.Input.ShortRows] {
+		return fmt.Errorf("unknown input short rows policy: %s", cfg.Input.ShortRows)
+	}
+
+	if !validLongRowPolicy[cfg.Input.LongRows] {
+		return fmt.Errorf("unknown input long rows policy: %s", cfg.Input.LongRows)
+	}
+
 	for i := range cfg.Input.Fields {
 		f := &cfg.Input.Fields[i]
 
@@ -236,11 +631,150 @@ func (cfg *Config) setInputDefaults() error {
 	return nil
 }
 
+func (cfg *Config) setJoinDefaults() error {
+	aliases := map[string]bool{}
+	for i := range cfg.Input.Joins {
+		join := &cfg.Input.Joins[i]
+
+		if join.Alias == "" {
+			return fmt.Errorf("join alias is required")
+		}
+
+		if strings.Contains(join.Alias, JoinAliasSeparator) {
+			return fmt.Errorf("join alias '%s' must not contain '%s'", join.Alias, JoinAliasSeparator)
+		}
+
+		if aliases[join.Alias] {
+			return fmt.Errorf("join alias '%s' is used more than once", join.Alias)
+		}
+
+		aliases[join.Alias] = true
+
+		if join.Key == "" {
+			return fmt.Errorf("join '%s': key is required", join.Alias)
+		}
+
+		if join.On == "" {
+			join.On = join.Key
+		}
+
+		if join.Type == "" {
+			join.Type = DefaultJoinType
+		}
+
+		if !validJoinType[join.Type] {
+			return fmt.Errorf("join '%s': unknown join type: %s", join.Alias, join.Type)
+		}
+
+		if join.Index == "" {
+			join.Index = DefaultJoinIndex
+		}
+
+		if !validJoinIndex[join.Index] {
+			return fmt.Errorf("join '%s': unknown join index: %s", join.Alias, join.Index)
+		}
+
+		if len(join.Input.Joins) > 0 {
+			return fmt.Errorf("join '%s': nested join is not supported", join.Alias)
+		}
+
+		if join.Path == StdinPath {
+			return fmt.Errorf("join '%s': secondary input can't be read from stdin", join.Alias)
+		}
+
+		joinCfg := &Config{
+			Args:      &Args{ConfigPath: cfg.Args.ConfigPath, InputPath: join.Path},
+			BatchSize: cfg.BatchSize,
+			Parser:    cfg.Parser,
+			Input:     join.Input,
+		}
+
+		if err := joinCfg.setInputDefaults(); err != nil {
+			return fmt.Errorf("join '%s': %s", join.Alias, err)
+		}
+
+		if joinCfg.Input.IsFile() && join.Path == "" {
+			return fmt.Errorf("join '%s': path is required for %s input", join.Alias, joinCfg.Input.Type)
+		}
+
+		joinCfg.assignFieldsOrder()
+		joinCfg.constructHelperMaps()
+		join.Config = joinCfg
+	}
+
+	return nil
+}
+
 func (cfg *Config) setOutputDefaults() error {
 	if cfg.Output.Type == "" {
 		cfg.Output.Type = DefaultOutputType
 	}
 
+	if cfg.Output.Path == "" {
+		cfg.Output.Path = DefaultOutputPath + "." + cfg.Output.Type
+	}
+
+	return nil
+}
+
+func (cfg *Config) setFailedRowsDefaults() error {
+	failedType := cfg.Input.Type
+	if csvFailedRowsInputTypes[failedType] {
+		failedType = DefaultInputType
+	}
+
+	// failed rows file is not written next to multiple input files, so it's not matched by input path in the next run
+	if cfg.FailedRows.Path == "" && (!cfg.Args.HasInputFile() || cfg.Args.HasInputPattern()) {
+		cfg.FailedRows.Path = DefaultFailedRowsPath + "." + failedType
+	}
+
+	// failed rows file is never compressed, e.g. data.csv.gz become data.failed.csv and data.zip become data.failed.csv
+	if cfg.FailedRows.Path == "" {
+		base := cfg.Args.InputPath
+		if ext := filepath.Ext(base); compressedFileExtensions[strings.ToLower(ext)] {
+			base = strings.TrimSuffix(base, ext)
+		}
+
+		ext := filepath.Ext(base)
+		if ext == "" {
+			ext = "." + cfg.Input.Type
+		}
+
+		base = strings.TrimSuffix(base, ext)
+		if failedType != cfg.Input.Type {
+			ext = "." + failedType
+		}
+
+		cfg.FailedRows.Path = base + "." + DefaultFailedRowsPath + ext
+	}
+
+	return nil
+}
+
+func (cfg *Config) setCheckPointDefaults() error {
+	cp := &cfg.CheckPoint
+
+	if cp.Type == "" {
+		cp.Type = DefaultCheckPointStoreType
+	}
+
+	if cp.Host == "" {
+		cp.Host = DefaultHost
+	}
+
+	if cp.Port == 0 {
+		cp.Port = getDefaultPort(TargetType(cp.Type))
+	}
+
+	if cp.DataName == "" {
+		cp.DataName = DefaultCheckPointTable
+	}
+
+	// same config and input file share the same checkpoint
+	if cp.Key == "" {
+		cp.Key = fmt.Sprintf("%s:%s:%s", DefaultCheckPointKeyPrefix, cfg.Args.ConfigPath, cfg.Args.InputPath)
+	}
+
 	return nil
 }
 
@@ -272,6 +806,17 @@ func (cfg *Config) setTargetDefaults() error {
 			t.Mode = TargetModeInsert
 		}
 
+		budget, err := parseErrorBudget(t.MaxErrors)
+		if err != nil {
+			return fmt.Errorf("[Target ID: %s] %s", t.ID, err)
+		}
+
+		t.ErrorBudget = budget
+
+		if err := t.Retry.setDefaults(); err != nil {
+			return fmt.Errorf("[Target ID: %s] %s", t.ID, err)
+		}
+
 		for j := range t.Fields {
 			f := &t.Fields[j]
 
@@ -399,6 +944,105 @@ func (cfg *Config) constructHelperArrays() {
 	}
 }
 
+func (retry *Retry) setDefaults() error {
+	if retry.MaxAttempts == 0 {
+		retry.MaxAttempts = DefaultRetryMaxAttempts
+	}
+
+	// backoff and jitter can be zero, so only missing value is replaced with default value
+	if retry.InitialBackoff == nil {
+		initialBackoff := DefaultRetryInitialBackoff
+		retry.InitialBackoff = &initialBackoff
+	}
+
+	if retry.MaxBackoff == nil {
+		maxBackoff := DefaultRetryMaxBackoff
+		retry.MaxBackoff = &maxBackoff
+	}
+
+	if retry.Multiplier == 0 {
+		retry.Multiplier = DefaultRetryMultiplier
+	}
+
+	if retry.Jitter == nil {
+		jitter := DefaultRetryJitter
+		retry.Jitter = &jitter
+	}
+
+	if retry.MaxAttempts < 1 || *retry.InitialBackoff < 0 || *retry.MaxBackoff < 0 || retry.Multiplier < 1 {
+		return fmt.Errorf("invalid retry policy: max attempts %d, initial backoff %d, max backoff %d, multiplier %v", retry.MaxAttempts, *retry.InitialBackoff, *retry.MaxBackoff, retry.Multiplier)
+	}
+
+	if *retry.Jitter < 0 || *retry.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// check whether total failed rows has used up the budget, percentage is relative to total processed rows
+func (budget ErrorBudget) IsExceeded(failedRows, processedRows int) bool {
+	if failedRows == 0 {
+		return false
+	}
+
+	if budget.IsRelative {
+		return float64(failedRows)*100 > budget.Percentage*float64(processedRows)
+	}
+
+	return failedRows > budget.Count
+}
+
+func parseErrorBudget(raw string) (res ErrorBudget, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return res, nil
+	}
+
+	if strings.HasSuffix(raw, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(raw, "%")), 64)
+		if err != nil || pct < 0 || pct > 100 {
+			return res, fmt.Errorf("invalid max errors percentage: %s", raw)
+		}
+
+		return ErrorBudget{Percentage: pct, IsRelative: true}, nil
+	}
+
+	count, err := strconv.Atoi(raw)
+	if err != nil || count < 0 {
+		return res, fmt.Errorf("invalid max errors value: %s", raw)
+	}
+
+	return ErrorBudget{Count: count}, nil
+}
+
+// single character, or its name for characters which are hard to write in yaml such as tab
+func parseInputRune(raw string, defaultValue rune) (rune, error) {
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	if named, exists := namedInputRunes[strings.ToLower(raw)]; exists {
+		return named, nil
+	}
+
+	runes := []rune(raw)
+	if len(runes) > 1 {
+		return 0, fmt.Errorf("must be a single character")
+	}
+
+	if runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' || runes[0] == utf8.RuneError {
+		return 0, fmt.Errorf("character %q is not allowed", runes[0])
+	}
+
+	return runes[0], nil
+}
+
+// false if input is read from a server or generated instead of read from input file
+func (input *Input) IsFile() bool {
+	return !nonFileInputTypes[input.Type]
+}
+
 func getDefaultPort(targetType TargetType) int {
 	switch TargetType(targetType) {
 	case TargetTypeMySQL:
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ridwanadhip/universal-uploader/util"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		Input: Input{
+			Password: "input-secret",
+			Joins: []Join{{
+				Alias:  "codes",
+				Input:  Input{Password: "join-secret"},
+				Config: &Config{Input: Input{Password: "join-config-secret"}},
+			}},
+		},
+		Targets:    []Target{{ID: "t1", Password: "target-secret"}},
+		CheckPoint: CheckPointStore{Password: "checkpoint-secret"},
+	}
+
+	exported := util.Jsonify(cfg.Redacted())
+	for _, secret := range []string{"input-secret", "join-secret", "join-config-secret", "target-secret", "checkpoint-secret"} {
+		if strings.Contains(exported, secret) {
+			t.Errorf("exported config contains %s: %s", secret, exported)
+		}
+	}
+
+	// original config is not modified
+	if cfg.Input.Password != "input-secret" || cfg.Input.Joins[0].Input.Password != "join-secret" || cfg.Targets[0].Password != "target-secret" {
+		t.Errorf("original config is modified")
+	}
+}
+
+func TestInputFingerprint(t *testing.T) {
+	hasHeader := true
+	newInput := func() Input {
+		return Input{
+			Type:      "csv",
+			HasHeader: &hasHeader,
+			HeaderRow: 1,
+			Delimiter: ',',
+			Username:  "user",
+			Password:  "secret",
+			Fields:    []InputField{{ID: "id", Name: "id"}},
+			Joins:     []Join{{Alias: "codes", Path: "codes.csv", Key: "code", Input: Input{Type: "csv", Password: "secret"}}},
+		}
+	}
+
+	base := newInput()
+	want := base.Fingerprint()
+
+	tests := []struct {
+		name    string
+		modify  func(input *Input)
+		changed bool
+	}{
+		{"credentials", func(input *Input) { input.Username, input.Password = "other", "other" }, false},
+		{"join credentials", func(input *Input) { input.Joins[0].Input.Password = "other" }, false},
+		{"input files", func(input *Input) { input.Files = []string{"part-1.csv"} }, false},
+		{"skip lines", func(input *Input) { input.SkipLines = 1 }, true},
+		{"header row", func(input *Input) { input.HeaderRow = 2 }, true},
+		{"has header", func(input *Input) { noHeader := false; input.HasHeader = &noHeader }, true},
+		{"delimiter", func(input *Input) { input.Delimiter = ';' }, true},
+		{"trim spaces", func(input *Input) { input.TrimSpaces = true }, true},
+		{"field order", func(input *Input) { order := 1; input.Fields[0].Order = &order }, true},
+		{"join key", func(input *Input) { input.Joins[0].Key = "old_code" }, true},
+		{"join input", func(input *Input) { input.Joins[0].Input.SkipLines = 1 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := newInput()
+			tt.modify(&input)
+
+			if changed := input.Fingerprint() != want; changed != tt.changed {
+				t.Errorf("got changed fingerprint %v, want %v", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestCheckPointVerifyInputConfig(t *testing.T) {
+	saved := &CheckPoint{InputConfigHash: "a"}
+	if err := saved.Verify(&CheckPoint{InputConfigHash: "b"}); err == nil || !strings.Contains(err.Error(), "input config has been changed") {
+		t.Errorf("got error %v, want changed input config", err)
+	}
+
+	if err := saved.Verify(&CheckPoint{InputConfigHash: "a"}); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+
+	// checkpoint from older version doesn't have input config hash
+	if err := (&CheckPoint{}).Verify(&CheckPoint{InputConfigHash: "b"}); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import "testing"
+
+func TestRetrySetDefaults(t *testing.T) {
+	zero := 0
+	zeroJitter := 0.0
+	backoff := 100
+	jitter := 0.5
+	invalidJitter := 1.5
+	negative := -1
+
+	tests := []struct {
+		name        string
+		retry       Retry
+		wantErr     bool
+		wantBackoff int
+		wantMax     int
+		wantJitter  float64
+	}{
+		{"missing values use defaults", Retry{}, false, DefaultRetryInitialBackoff, DefaultRetryMaxBackoff, DefaultRetryJitter},
+		{"zero backoff and jitter are kept", Retry{InitialBackoff: &zero, MaxBackoff: &zero, Jitter: &zeroJitter}, false, 0, 0, 0},
+		{"configured values are kept", Retry{InitialBackoff: &backoff, Jitter: &jitter}, false, 100, DefaultRetryMaxBackoff, 0.5},
+		{"negative backoff", Retry{InitialBackoff: &negative}, true, 0, 0, 0},
+		{"jitter larger than 1", Retry{Jitter: &invalidJitter}, true, 0, 0, 0},
+		{"multiplier less than 1", Retry{Multiplier: 0.5}, true, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry := tt.retry
+			err := retry.setDefaults()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", retry)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if retry.MaxAttempts != DefaultRetryMaxAttempts || retry.Multiplier != DefaultRetryMultiplier {
+				t.Errorf("unexpected max attempts %d or multiplier %v", retry.MaxAttempts, retry.Multiplier)
+			}
+
+			if *retry.InitialBackoff != tt.wantBackoff || *retry.MaxBackoff != tt.wantMax || *retry.Jitter != tt.wantJitter {
+				t.Errorf("got backoff %d, max %d, jitter %v", *retry.InitialBackoff, *retry.MaxBackoff, *retry.Jitter)
+			}
+		})
+	}
+}
+
+func TestErrorBudget(t *testing.T) {
+	tests := []struct {
+		raw           string
+		wantErr       bool
+		failedRows    int
+		processedRows int
+		wantExceeded  bool
+	}{
+		{"", false, 0, 10, false},
+		{"", false, 1, 10, true},
+		{"2", false, 2, 10, false},
+		{"2", false, 3, 10, true},
+		{"10%", false, 1, 10, false},
+		{"10%", false, 2, 10, true},
+		{" 50 % ", false, 5, 10, false},
+		{"-1", true, 0, 0, false},
+		{"101%", true, 0, 0, false},
+		{"abc", true, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		budget, err := parseErrorBudget(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.raw)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: %s", tt.raw, err)
+			continue
+		}
+
+		if exceeded := budget.IsExceeded(tt.failedRows, tt.processedRows); exceeded != tt.wantExceeded {
+			t.Errorf("%q: %d of %d rows failed, exceeded is %v", tt.raw, tt.failedRows, tt.processedRows, exceeded)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package input
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/ridwanadhip/universal-uploader/config"
+	"github.com/ridwanadhip/universal-uploader/util"
 )
 
 type (
@@ -13,18 +17,57 @@ type (
 
 // known types
 const (
-	InputTypeCSV InputType = "csv"
+	InputTypeCSV        InputType = "csv"
+	InputTypeJSON       InputType = "json"   // top-level array of objects
+	InputTypeNDJSON     InputType = "ndjson" // one object per line
+	InputTypeXLSX       InputType = "xlsx"
+	InputTypeFixedWidth InputType = "fixedwidth" // columns at fixed character positions
+	InputTypeMySQL      InputType = "mysql"      // rows of select query
+	InputTypeRedis      InputType = "redis"      // keys matched by scan pattern
+	InputTypeParquet    InputType = "parquet"
+	InputTypeXML        InputType = "xml"      // elements matched by record path
+	InputTypeGenerate   InputType = "generate" // rows generated from field generators
 )
 
+// name pattern of temporary copy of input stream
+const TempFilePattern = "universal-uploader-input-*"
+
+// if input path is a glob pattern or a directory then the matched files are read one after another
+// as a single stream, so index of data rows continues across files
 type Parser struct {
 	cfg         *config.Config
 	inputParser InputParser
 	fieldNames  []string
+	joiners     []*joiner // secondary inputs, their columns are appended after columns of input file
+	joinedNames []string  // field names of secondary inputs, prefixed by join alias
+	path        string    // input file, or temporary copy of input stream
+	tempPath    string    // removed when parser is closed
+	files       []string  // files matched by input path pattern, nil if input path is a single file
+	fileIndex   int       // position of the current file in files
+	fileStart   int       // 0 based index of the first data row of the current file
+	nextIndex   int       // 0 based index of the next data row
 }
 
 type Batch struct {
-	Data  [][]string
-	Index int
+	Data      [][]string
+	Raw       [][]string    // original rows before pre-processed, used for writing failed rows
+	Index     int           // 0 based index of the first row which is read in batch, including rejected rows
+	Indexes   []int         // 0 based index of each row, followed by index of the next row after batch. Nil if no row is rejected or skipped
+	Offsets   []int64       // byte offset of each row in input file, followed by offset after the last row. Nil if unknown
+	Rejected  []RejectedRow // rows skipped by input config, they are not included in data
+	Nulls     [][]bool      // true if the value of data is null, nil if input type doesn't have null value
+	File      string        // input file of the rows if input path matches more than one file
+	FileStart int           // 0 based index of the first data row of the file
+
+	joined      [][]string // columns of secondary inputs of each row, nil if input doesn't have join
+	joinedNulls [][]bool
+}
+
+// row which is skipped before uploaded, line is the 1 based position of row in input file
+type RejectedRow struct {
+	Line int
+	Row  []string
+	Err  error
 }
 
 type InputParser interface {
@@ -34,29 +77,225 @@ type InputParser interface {
 	Close()
 }
 
+// implemented by input parser which can continue reading from byte offset of a data row
+type RowSeeker interface {
+	SeekRow(path string, offset int64, index int) error
+}
+
+// input is read from stdin if input path is -
 func NewParser(cfg *config.Config) (parser Parser, err error) {
+	if cfg.Args.InputPath == config.StdinPath {
+		return NewReaderParser(cfg, os.Stdin)
+	}
+
+	if cfg.Input.IsFile() && cfg.Args.InputPath == "" {
+		return Parser{}, fmt.Errorf("input file path is required for %s input", cfg.Input.Type)
+	}
+
+	inputParser, err := newInputParser(cfg)
+	if err != nil {
+		return Parser{}, err
+	}
+
+	parser = Parser{cfg: cfg, inputParser: inputParser, path: cfg.Args.InputPath}
+	if cfg.Args.HasInputPattern() {
+		parser.files = cfg.Input.Files
+		parser.path = parser.files[0]
+	}
+
+	return parser, parser.init()
+}
+
+// read input from stream instead of input file. CSV is parsed while it's streamed, while other types
+// need to be read more than once so the stream is copied to a temporary file first
+func NewReaderParser(cfg *config.Config, r io.Reader) (parser Parser, err error) {
+	if InputType(cfg.Input.Type) == InputTypeCSV {
+		parser = Parser{cfg: cfg, inputParser: &csvParser{cfg: &cfg.Input, batchSize: cfg.BatchSize, stream: r}}
+		return parser, parser.init()
+	}
+
+	inputParser, err := newInputParser(cfg)
+	if err != nil {
+		return Parser{}, err
+	}
+
+	tempPath, err := util.CopyToTempFile(r, TempFilePattern)
+	if err != nil {
+		return Parser{}, err
+	}
+
+	parser = Parser{cfg: cfg, inputParser: inputParser, path: tempPath, tempPath: tempPath}
+	if err := parser.init(); err != nil {
+		parser.Close()
+		return parser, err
+	}
+
+	return parser, nil
+}
+
+// read rows from a source which is not a file, e.g. rows which are already in memory
+func NewRowSourceParser(cfg *config.Config, source RowSource) (parser Parser, err error) {
+	parser = Parser{cfg: cfg, inputParser: &rowSourceParser{source: source, batchSize: cfg.BatchSize}}
+	return parser, parser.init()
+}
+
+func newInputParser(cfg *config.Config) (InputParser, error) {
 	switch InputType(cfg.Input.Type) {
 	case InputTypeCSV:
-		parser = Parser{cfg: cfg, inputParser: &csvParser{batchSize: cfg.BatchSize}}
-	default:
-		return Parser{}, fmt.Errorf("unknown input parser type: %s", cfg.Input.Type)
+		return &csvParser{cfg: &cfg.Input, batchSize: cfg.BatchSize}, nil
+	case InputTypeJSON:
+		return newJSONParser(&cfg.Input, cfg.BatchSize, false), nil
+	case InputTypeNDJSON:
+		return newJSONParser(&cfg.Input, cfg.BatchSize, true), nil
+	case InputTypeXLSX:
+		return &xlsxParser{cfg: &cfg.Input, batchSize: cfg.BatchSize}, nil
+	case InputTypeFixedWidth:
+		return newFixedWidthParser(&cfg.Input, cfg.BatchSize)
+	case InputTypeMySQL:
+		return newMySQLParser(&cfg.Input, cfg.BatchSize)
+	case InputTypeRedis:
+		return newRedisParser(&cfg.Input, cfg.BatchSize)
+	case InputTypeParquet:
+		return &parquetParser{cfg: &cfg.Input, batchSize: cfg.BatchSize}, nil
+	case InputTypeXML:
+		return newXMLParser(&cfg.Input, cfg.BatchSize)
+	case InputTypeGenerate:
+		return newGenerateParser(&cfg.Input, cfg.BatchSize)
 	}
 
-	// inject fields if user didn't define field spec in config file
-	fieldNames, err := parser.inputParser.GetFieldNames(cfg.Args.InputPath)
+	return nil, fmt.Errorf("unknown input parser type: %s", cfg.Input.Type)
+}
+
+// inject fields if user didn't define field spec in config file
+func (parser *Parser) init() error {
+	fieldNames, err := parser.inputParser.GetFieldNames(parser.path)
 	if err != nil {
-		return parser, err
+		return parser.wrapFileError(err)
 	}
 
 	parser.fieldNames = fieldNames
-	cfg.InjectFieldsWithDefaultValue(fieldNames)
 
-	return parser, nil
+	if err := parser.checkFileHeaders(); err != nil {
+		return err
+	}
+
+	if err := parser.initJoiners(); err != nil {
+		return err
+	}
+
+	parser.cfg.InjectFieldsWithDefaultValue(parser.fieldNames)
+	parser.cfg.InjectJoinFields(parser.joinedNames, len(parser.fieldNames))
+
+	return nil
+}
+
+// secondary inputs are indexed before the first row is read
+func (parser *Parser) initJoiners() error {
+	for i := range parser.cfg.Input.Joins {
+		joiner, err := newJoiner(&parser.cfg.Input.Joins[i], parser.fieldNames)
+		if err != nil {
+			return err
+		}
+
+		parser.joiners = append(parser.joiners, joiner)
+		parser.joinedNames = append(parser.joinedNames, joiner.columns...)
+	}
+
+	return nil
 }
 
+// field names of input file followed by field names of secondary inputs
+func (parser *Parser) getAllFieldNames() []string {
+	if len(parser.joinedNames) == 0 {
+		return parser.fieldNames
+	}
+
+	res := append([]string{}, parser.fieldNames...)
+	return append(res, parser.joinedNames...)
+}
+
+// header of every matched file is compared with the first file before any row is uploaded, so
+// inconsistent file doesn't stop the run in the middle
+func (parser *Parser) checkFileHeaders() error {
+	for i := 1; i < len(parser.files); i++ {
+		inputParser, err := newInputParser(parser.cfg)
+		if err != nil {
+			return err
+		}
+
+		fieldNames, err := inputParser.GetFieldNames(parser.files[i])
+		inputParser.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", parser.files[i], err)
+		}
+
+		if err := parser.compareHeader(parser.files[i], fieldNames); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (parser *Parser) compareHeader(path string, fieldNames []string) error {
+	equal := len(fieldNames) == len(parser.fieldNames)
+	for i := 0; equal && i < len(fieldNames); i++ {
+		equal = fieldNames[i] == parser.fieldNames[i]
+	}
+
+	if !equal {
+		return fmt.Errorf("header of input file %s is different from %s, expected '%s' but got '%s'", path, parser.files[0], strings.Join(parser.fieldNames, ","), strings.Join(fieldNames, ","))
+	}
+
+	return nil
+}
+
+// continue reading from the file at index of matched files, start is the 0 based index of its first data row
+func (parser *Parser) openFile(index int, start int) error {
+	inputParser, err := newInputParser(parser.cfg)
+	if err != nil {
+		return err
+	}
+
+	path := parser.files[index]
+	fieldNames, err := inputParser.GetFieldNames(path)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	} else {
+		err = parser.compareHeader(path, fieldNames)
+	}
+
+	if err != nil {
+		inputParser.Close()
+		return err
+	}
+
+	parser.inputParser.Close()
+	parser.inputParser = inputParser
+	parser.path = path
+	parser.fileIndex = index
+	parser.fileStart = start
+	parser.nextIndex = start
+
+	return nil
+}
+
+// line number of error is relative to the file, so the file is included if input path matches more than one file
+func (parser *Parser) wrapFileError(err error) error {
+	if parser.files == nil {
+		return err
+	}
+
+	return fmt.Errorf("%s: %w", parser.path, err)
+}
+
+// field names are not compared if input file doesn't have header, so fields are mapped by order only
 func (parser *Parser) Validate() error {
+	hasHeader := parser.cfg.Input.HasHeader == nil || *parser.cfg.Input.HasHeader
+	fieldNames := parser.getAllFieldNames()
+
 	totalInputFields := len(parser.cfg.Input.Fields)
-	if totalInputFields > len(parser.fieldNames) {
+	if hasHeader && totalInputFields > len(fieldNames) {
 		return fmt.Errorf("the total fields in input file is less than total fields in config")
 	}
 
@@ -67,7 +306,15 @@ func (parser *Parser) Validate() error {
 			return fmt.Errorf("unable to determine order of config field '%s' from input file", f.Name)
 		}
 
-		fieldName := parser.fieldNames[*f.Order]
+		if *f.Order < 0 || *f.Order >= len(fieldNames) {
+			return fmt.Errorf("config field '%s' order %d is out of range, input file has %d fields", f.Name, *f.Order, len(fieldNames))
+		}
+
+		if !hasHeader {
+			continue
+		}
+
+		fieldName := fieldNames[*f.Order]
 		if f.Name != fieldName {
 			return fmt.Errorf("config field '%s' is referencing to wrong input field '%s'", f.Name, fieldName)
 		}
@@ -77,32 +324,293 @@ func (parser *Parser) Validate() error {
 }
 
 func (parser *Parser) NextBatch() (batch *Batch, exists bool, err error) {
-	batch, exists, err = parser.inputParser.NextBatch(parser.cfg.Args.InputPath)
-	if err != nil || !exists {
-		return batch, exists, err
+	for {
+		batch, exists, err = parser.inputParser.NextBatch(parser.path)
+		if err != nil {
+			return nil, false, parser.wrapFileError(err)
+		}
+
+		if exists {
+			break
+		}
+
+		if parser.fileIndex+1 >= len(parser.files) {
+			return nil, false, nil
+		}
+
+		if err := parser.openFile(parser.fileIndex+1, parser.nextIndex); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := parser.filterRows(batch); err != nil {
+		return nil, false, parser.wrapFileError(err)
+	}
+
+	if err := parser.preProcessBatchData(batch); err != nil {
+		return nil, false, parser.wrapFileError(err)
 	}
 
-	parser.preProcessBatchData(batch)
+	batch.Index += parser.fileStart
+	for i := range batch.Indexes {
+		batch.Indexes[i] += parser.fileStart
+	}
+
+	parser.nextIndex = batch.RowIndex(len(batch.Data))
+
+	if parser.files != nil {
+		batch.File = parser.path
+		batch.FileStart = parser.fileStart
+	}
 
 	return batch, exists, nil
 }
 
+// part of batch from start to end position, 0 based and end is exclusive
+func (batch *Batch) Slice(start, end int) *Batch {
+	res := &Batch{
+		Data:      batch.Data[start:end],
+		Index:     batch.RowIndex(start),
+		File:      batch.File,
+		FileStart: batch.FileStart,
+	}
+
+	if batch.Indexes != nil {
+		res.Indexes = batch.Indexes[start : end+1]
+	}
+
+	if batch.Raw != nil {
+		res.Raw = batch.Raw[start:end]
+	}
+
+	if batch.Offsets != nil {
+		res.Offsets = batch.Offsets[start : end+1]
+	}
+
+	if batch.Nulls != nil {
+		res.Nulls = batch.Nulls[start:end]
+	}
+
+	return res
+}
+
+// 0 based index of a row in input, position equal to total rows means the index of the next row after
+// batch. Rejected rows are counted, so index is the real position of row in input
+func (batch *Batch) RowIndex(pos int) int {
+	if batch.Indexes == nil {
+		return batch.Index + pos
+	}
+
+	return batch.Indexes[pos]
+}
+
+// 1 based lines of rows from start to end position for messages, end is inclusive. If input path matches
+// more than one file then lines are relative to the file and prefixed by its path, e.g. part-2.csv: line 3
+func (batch *Batch) DescribeLines(start, end int) string {
+	res := fmt.Sprintf("line %d", batch.RowIndex(start)-batch.FileStart+1)
+	if end != start {
+		res += fmt.Sprintf(" to %d", batch.RowIndex(end)-batch.FileStart+1)
+	}
+
+	if batch.File == "" {
+		return res
+	}
+
+	return batch.File + ": " + res
+}
+
+// index of every row in batch
+func (batch *Batch) RowIndexes() []int {
+	res := make([]int, len(batch.Data))
+	for pos := range res {
+		res[pos] = batch.RowIndex(pos)
+	}
+
+	return res
+}
+
+// byte offset of a row in batch, position equal to total rows means the offset after the last row
+func (batch *Batch) GetOffset(pos int) (offset int64, exists bool) {
+	if batch.Offsets == nil {
+		return 0, false
+	}
+
+	return batch.Offsets[pos], true
+}
+
+// continue reading input from position of a data row, returns false if seeking is not supported by
+// the input type. If input path matches more than one file then reading continues from the file of
+// the row, even if its byte offset is unknown
+func (parser *Parser) SeekRow(pos config.InputPosition) (bool, error) {
+	// temporary copy of input stream is a different file when resumed
+	if parser.tempPath != "" {
+		return false, nil
+	}
+
+	seeked := false
+	if parser.files != nil {
+		index := -1
+		for i, path := range parser.files {
+			if path == pos.File {
+				index = i
+				break
+			}
+		}
+
+		if index < 0 {
+			return false, nil
+		}
+
+		if err := parser.openFile(index, pos.Row-pos.FileRow); err != nil {
+			return false, err
+		}
+
+		parser.nextIndex = pos.Row
+		seeked = true
+	}
+
+	seeker, ok := parser.inputParser.(RowSeeker)
+	if !ok || pos.Offset < 0 {
+		return seeked, nil
+	}
+
+	err := seeker.SeekRow(parser.path, pos.Offset, pos.Row-parser.fileStart)
+	if errors.Is(err, errSeekNotSupported) {
+		return seeked, nil
+	}
+
+	return err == nil, err
+}
+
 func (parser *Parser) GetCurrentIndex() int {
-	return parser.inputParser.GetCurrentIndex()
+	return parser.fileStart + parser.inputParser.GetCurrentIndex()
 }
 
 func (parser *Parser) Close() {
 	if parser.inputParser != nil {
 		parser.inputParser.Close()
 	}
+
+	for _, joiner := range parser.joiners {
+		joiner.Close()
+	}
+
+	parser.joiners = nil
+
+	if parser.tempPath != "" {
+		os.Remove(parser.tempPath)
+		parser.tempPath = ""
+	}
+}
+
+// apply ragged rows policy of input config to rows which total columns is different from total fields
+// of input file, then join rows with secondary inputs. Skipped rows are removed from batch, and index of
+// the remaining rows is kept in batch indexes, including indexes which are already set by input parser
+func (parser *Parser) filterRows(batch *Batch) error {
+	totalFields := len(parser.fieldNames)
+
+	data := [][]string{}
+	indexes := []int{}
+	offsets := []int64{}
+	nulls := [][]bool{}
+	joined := [][]string{}
+	joinedNulls := [][]bool{}
+	for i, row := range batch.Data {
+		policy := config.RaggedRowPolicy("")
+		if len(row) < totalFields {
+			policy = parser.cfg.Input.ShortRows
+		} else if len(row) > totalFields {
+			policy = parser.cfg.Input.LongRows
+		}
+
+		line := batch.RowIndex(i) + 1
+		err := fmt.Errorf("line %d: wrong number of fields, expected %d but got %d", line, totalFields, len(row))
+
+		switch policy {
+		case config.RaggedRowError:
+			return err
+		case config.RaggedRowSkip:
+			batch.Rejected = append(batch.Rejected, RejectedRow{Line: line, Row: row, Err: parser.wrapFileError(err)})
+			continue
+		case config.RaggedRowPad:
+			row = append(row, make([]string, totalFields-len(row))...)
+		case config.RaggedRowTruncate:
+			row = row[:totalFields]
+		}
+
+		if len(parser.joiners) > 0 {
+			values, valueNulls, missing, err := parser.joinRow(row)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", line, err)
+			}
+
+			if missing != nil {
+				err := fmt.Errorf("line %d: no matching row in join '%s' for %s '%s'", line, missing.join.Alias, missing.join.On, row[missing.onIndex])
+				batch.Rejected = append(batch.Rejected, RejectedRow{Line: line, Row: row, Err: parser.wrapFileError(err)})
+				continue
+			}
+
+			joined = append(joined, values)
+			joinedNulls = append(joinedNulls, valueNulls)
+		}
+
+		data = append(data, row)
+		indexes = append(indexes, batch.RowIndex(i))
+		if batch.Offsets != nil {
+			offsets = append(offsets, batch.Offsets[i])
+		}
+
+		if batch.Nulls != nil {
+			nulls = append(nulls, batch.Nulls[i])
+		}
+	}
+
+	if batch.Offsets != nil {
+		batch.Offsets = append(offsets, batch.Offsets[len(batch.Offsets)-1])
+	}
+
+	if batch.Nulls != nil {
+		batch.Nulls = nulls
+	}
+
+	if len(parser.joiners) > 0 {
+		batch.joined = joined
+		batch.joinedNulls = joinedNulls
+	}
+
+	if len(batch.Rejected) > 0 || batch.Indexes != nil {
+		batch.Indexes = append(indexes, batch.RowIndex(len(batch.Data)))
+	}
+
+	batch.Data = data
+
+	return nil
 }
 
-func (parser *Parser) preProcessBatchData(batch *Batch) {
+func (parser *Parser) preProcessBatchData(batch *Batch) error {
 	fields := parser.cfg.Input.Fields
 
 	preProcessedData := [][]string{}
+	preProcessedNulls := [][]bool{}
 	for i := range batch.Data {
 		data := []string{}
+		nulls := []bool{}
+
+		// columns of secondary inputs are placed after columns of input file, so they are picked by
+		// field order like the other columns
+		row := batch.Data[i]
+		var rowNulls []bool
+		if batch.Nulls != nil {
+			rowNulls = batch.Nulls[i]
+		}
+
+		if batch.joined != nil {
+			row = append(append([]string{}, row...), batch.joined[i]...)
+
+			joinedNulls := make([]bool, len(batch.Data[i]))
+			copy(joinedNulls, rowNulls)
+			rowNulls = append(joinedNulls, batch.joinedNulls[i]...)
+		}
 
 		for j := range fields {
 			f := &fields[j]
@@ -112,16 +620,33 @@ func (parser *Parser) preProcessBatchData(batch *Batch) {
 				fieldOrder = *f.Order
 			}
 
-			val := batch.Data[i][fieldOrder]
+			if fieldOrder < 0 || fieldOrder >= len(row) {
+				return fmt.Errorf("line %d: field '%s' order %d is out of range, row has %d fields", batch.RowIndex(i)+1, f.Name, fieldOrder, len(row))
+			}
+
+			val := row[fieldOrder]
 			if f.TrimSpaces || parser.cfg.Input.TrimSpaces {
 				val = strings.TrimSpace(val)
 			}
 
 			data = append(data, val)
+
+			if rowNulls != nil {
+				nulls = append(nulls, fieldOrder < len(rowNulls) && rowNulls[fieldOrder])
+			}
 		}
 
 		preProcessedData = append(preProcessedData, data)
+		preProcessedNulls = append(preProcessedNulls, nulls)
 	}
 
+	batch.Raw = batch.Data
 	batch.Data = preProcessedData
+
+	// unmatched rows of left join are null, so rows have null flags even if input type doesn't
+	if batch.Nulls != nil || batch.joined != nil {
+		batch.Nulls = preProcessedNulls
+	}
+
+	return nil
 }
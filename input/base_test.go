@@ -0,0 +1,243 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+// write files into a temporary directory, the config is parsed the same way as the uploader does
+func newTestParser(t *testing.T, configYAML string, inputName string, files map[string]string) *Parser {
+	t.Helper()
+
+	parser, err := openTestParser(t, configYAML, inputName, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parser
+}
+
+// $DIR in config is replaced with the temporary directory, so config can refer to other files
+func openTestParser(t *testing.T, configYAML string, inputName string, files map[string]string) (*Parser, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML = strings.ReplaceAll(configYAML, "$DIR", dir)
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := &config.Args{ConfigType: string(config.ConfigTypeYAML), ConfigPath: configPath}
+	if inputName != "" {
+		args.InputPath = filepath.Join(dir, inputName)
+	}
+
+	cfgParser, err := config.NewParser(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := cfgParser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	parser, err := NewParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Cleanup(parser.Close)
+
+	return &parser, parser.Validate()
+}
+
+// read every batch, rows are returned with the 1 based line of each row
+func readAll(t *testing.T, parser *Parser) (rows [][]string, lines []int, batches []*Batch) {
+	t.Helper()
+
+	for {
+		batch, exists, err := parser.NextBatch()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !exists {
+			return rows, lines, batches
+		}
+
+		for pos := range batch.Data {
+			rows = append(rows, batch.Data[pos])
+			lines = append(lines, batch.RowIndex(pos)+1)
+		}
+
+		batches = append(batches, batch)
+	}
+}
+
+func TestRaggedRowsKeepRowIndex(t *testing.T) {
+	cfg := `
+batchSize: 3
+input:
+  shortRows: skip
+  longRows: skip
+`
+	data := "a,b\n1,x\n2\n3,y\n4,z,extra\n5,w\n"
+	parser := newTestParser(t, cfg, "data.csv", map[string]string{"data.csv": data})
+
+	rows, lines, batches := readAll(t, parser)
+
+	wantRows := [][]string{{"1", "x"}, {"3", "y"}, {"5", "w"}}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("got rows %v, want %v", rows, wantRows)
+	}
+
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+
+	rejected := []int{}
+	for _, batch := range batches {
+		for _, row := range batch.Rejected {
+			rejected = append(rejected, row.Line)
+		}
+	}
+
+	if want := []int{2, 4}; !reflect.DeepEqual(rejected, want) {
+		t.Errorf("got rejected lines %v, want %v", rejected, want)
+	}
+
+	// progress after the first batch includes the skipped row
+	if next := batches[0].RowIndex(len(batches[0].Data)); next != 3 {
+		t.Errorf("got next row index %d, want 3", next)
+	}
+
+	sliced := batches[0].Slice(1, 2)
+	if sliced.Index != 2 || sliced.RowIndex(0) != 2 || sliced.RowIndex(1) != 3 {
+		t.Errorf("got sliced index %d, rows %v", sliced.Index, sliced.Indexes)
+	}
+}
+
+func TestDescribeLines(t *testing.T) {
+	tests := []struct {
+		batch      Batch
+		start, end int
+		want       string
+	}{
+		{Batch{Data: make([][]string, 3), Index: 10}, 0, 2, "line 11 to 13"},
+		{Batch{Data: make([][]string, 3), Index: 10}, 1, 1, "line 12"},
+		{Batch{Data: make([][]string, 2), Index: 10, Indexes: []int{10, 12, 13}}, 0, 1, "line 11 to 13"},
+		{Batch{Data: make([][]string, 2), Index: 10, File: "part-2.csv", FileStart: 8}, 0, 1, "part-2.csv: line 3 to 4"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.batch.DescribeLines(tt.start, tt.end); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestGlobInputSeekRow(t *testing.T) {
+	files := map[string]string{
+		"parts/part-1.csv": "id,name\n1,a\n2,b\n3,c\n",
+		"parts/part-2.csv": "id,name\n4,d\n5,e\n6,f\n",
+	}
+
+	cfg := "batchSize: 2\n"
+
+	// position of the second row of the second file, recorded like checkpoint of uploader
+	parser := newTestParser(t, cfg, "parts", files)
+	_, _, batches := readAll(t, parser)
+
+	var pos config.InputPosition
+	for _, batch := range batches {
+		for i := range batch.Data {
+			if batch.Data[i][0] != "5" {
+				continue
+			}
+
+			offset, _ := batch.GetOffset(i)
+			pos = config.InputPosition{Row: batch.RowIndex(i), Offset: offset, File: batch.File, FileRow: batch.RowIndex(i) - batch.FileStart}
+		}
+	}
+
+	if pos.Row != 4 || pos.FileRow != 1 || !strings.HasSuffix(pos.File, "part-2.csv") {
+		t.Fatalf("unexpected position %+v", pos)
+	}
+
+	tests := []struct {
+		name      string
+		offset    int64
+		wantIndex int
+		wantRows  [][]string
+		wantLines []string
+	}{
+		{"byte offset", pos.Offset, 4, [][]string{{"5", "e"}, {"6", "f"}}, []string{"part-2.csv: line 2", "part-2.csv: line 3"}},
+		{"unknown offset", -1, 3, [][]string{{"4", "d"}, {"5", "e"}, {"6", "f"}}, []string{"part-2.csv: line 1", "part-2.csv: line 2", "part-2.csv: line 3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newTestParser(t, cfg, "parts", files)
+
+			// every parser has its own temporary directory
+			seekPos := pos
+			seekPos.File = parser.files[1]
+			seekPos.Offset = tt.offset
+
+			// files before the file of the row are never read again
+			seeked, err := parser.SeekRow(seekPos)
+			if err != nil || !seeked {
+				t.Fatalf("unable to seek: %v, %v", seeked, err)
+			}
+
+			// rows before current index are never read, so uploader records them as skipped without reading
+			if index := parser.GetCurrentIndex(); index != tt.wantIndex {
+				t.Errorf("got current index %d after seek, want %d", index, tt.wantIndex)
+			}
+
+			rows := [][]string{}
+			lines := []string{}
+			for {
+				batch, exists, err := parser.NextBatch()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !exists {
+					break
+				}
+
+				for i := range batch.Data {
+					rows = append(rows, batch.Data[i])
+					lines = append(lines, filepath.Base(batch.DescribeLines(i, i)))
+				}
+			}
+
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("got rows %v, want %v", rows, tt.wantRows)
+			}
+
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("got lines %v, want %v", lines, tt.wantLines)
+			}
+		})
+	}
+}
@@ -1,34 +1,61 @@
 package input
 
 import (
+	"bufio"
 	"encoding/csv"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+
+	"github.com/ridwanadhip/universal-uploader/config"
 )
 
+// name of column if input file doesn't have header, followed by 1 based column number
+const GeneratedFieldPrefix = "column"
+
 type csvParser struct {
+	cfg          *config.Input
 	batchSize    int
 	currentIndex int
-	file         *os.File
+	baseOffset   int64 // byte offset where reader start reading, not zero if the file is seeked or lines are skipped
+	file         *inputFile
+	stream       io.Reader // if not nil then input is read from this stream instead of input file
+	streamOpened bool      // stream can only be read once
 	reader       *csv.Reader
+	pending      []csvRecord // rows which are read ahead, so trailing rows can be skipped
+}
+
+type csvRecord struct {
+	columns []string
+	offset  int64
+}
+
+type csvFailedRowsWriter struct {
+	file        *os.File
+	writer      *csv.Writer
+	totalFields int
+	errorIndex  int
+	truncate    bool // if true then columns after total fields are dropped, see getConfiguredTotalFields
+	trailing    int  // placeholder rows which are written after the last row
 }
 
 func (parser *csvParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
-	reader := parser.reader
-	if reader == nil {
-		f, err := os.Open(path)
-		if err != nil {
+	// stream is already read until the end
+	if parser.reader == nil && parser.streamOpened {
+		return nil, false, nil
+	}
+
+	if parser.reader == nil {
+		if _, err := parser.open(path); err != nil {
 			return nil, false, err
 		}
-
-		parser.file = f
-		parser.reader = csv.NewReader(f)
-		reader = parser.reader
 	}
 
 	data := [][]string{}
-	for {
-		columns, err := reader.Read()
+	offsets := []int64{}
+	for len(data) < parser.batchSize {
+		record, err := parser.readRecord()
 		if err == io.EOF {
 			break
 		}
@@ -38,44 +65,82 @@ func (parser *csvParser) NextBatch(path string) (batch *Batch, exists bool, err
 		}
 
 		parser.currentIndex += 1
-
-		// skip header
-		if parser.currentIndex == 1 {
-			continue
-		}
-
-		data = append(data, columns)
-		if len(data) == parser.batchSize {
-			break
-		}
+		data = append(data, record.columns)
+		offsets = append(offsets, record.offset)
 	}
 
 	batch = &Batch{
 		Data:  data,
-		Index: parser.currentIndex - len(data) - 1,
+		Index: parser.currentIndex - len(data),
+	}
+
+	if !parser.file.transformed {
+		batch.Offsets = append(offsets, parser.getNextOffset())
 	}
 
 	// end of file
 	if len(batch.Data) == 0 {
-		parser.reset()
+		parser.Close()
 		return nil, false, nil
 	}
 
 	return batch, true, nil
 }
 
+// header of input file, or generated names based on total columns of the first row if input file doesn't have header.
+// Stream is only read once, so the reader is kept open and the first row is buffered for NextBatch
 func (parser *csvParser) GetFieldNames(path string) ([]string, error) {
-	f, err := os.Open(path)
+	scanner := parser
+	if parser.stream == nil {
+		scanner = &csvParser{cfg: parser.cfg}
+		defer scanner.Close()
+	}
+
+	header, err := scanner.open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if header != nil {
+		return header, nil
+	}
+
+	record, err := scanner.readRecord()
 	if err != nil {
 		return nil, err
 	}
 
-	defer f.Close()
+	if scanner == parser {
+		parser.pending = append([]csvRecord{*record}, parser.pending...)
+	}
 
-	csvReader := csv.NewReader(f)
-	header, err := csvReader.Read()
+	fieldNames := []string{}
+	for i := range record.columns {
+		fieldNames = append(fieldNames, GeneratedFieldPrefix+strconv.Itoa(i+1))
+	}
 
-	return header, err
+	return fieldNames, nil
+}
+
+// continue reading from byte offset of a data row, leading lines and header are already skipped
+func (parser *csvParser) SeekRow(path string, offset int64, index int) error {
+	if parser.stream != nil {
+		return errSeekNotSupported
+	}
+
+	parser.Close()
+
+	f, err := openInputFile(path, parser.cfg, offset)
+	if err != nil {
+		return err
+	}
+
+	parser.file = f
+	parser.reader = parser.newReader(f)
+	parser.baseOffset = offset
+	parser.currentIndex = index
+
+	return nil
 }
 
 func (parser *csvParser) GetCurrentIndex() int {
@@ -92,5 +157,177 @@ func (parser *csvParser) Close() {
 func (parser *csvParser) reset() {
 	parser.file = nil
 	parser.reader = nil
+	parser.pending = nil
 	parser.currentIndex = 0
+	parser.baseOffset = 0
+}
+
+// open file and skip leading lines, then read header if input file has header
+func (parser *csvParser) open(path string) (header []string, err error) {
+	var f *inputFile
+	if parser.stream != nil {
+		if parser.streamOpened {
+			return nil, fmt.Errorf("input stream can only be read once")
+		}
+
+		parser.streamOpened = true
+		f, err = openInputStream(parser.stream, parser.cfg)
+	} else {
+		f, err = openInputFile(path, parser.cfg, 0)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// leading lines may not be valid csv, so they are skipped as text
+	buffered := bufio.NewReader(f)
+	skipped := f.baseOffset
+	for i := 0; i < parser.cfg.SkipLines; i++ {
+		line, err := buffered.ReadString('\n')
+		skipped += int64(len(line))
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	parser.file = f
+	parser.reader = parser.newReader(buffered)
+	parser.baseOffset = skipped
+
+	if !*parser.cfg.HasHeader {
+		return nil, nil
+	}
+
+	// rows above header row are ignored
+	for i := 0; i < parser.cfg.HeaderRow; i++ {
+		header, err = parser.reader.Read()
+		if err != nil {
+			parser.Close()
+			return nil, err
+		}
+	}
+
+	return header, nil
+}
+
+func (parser *csvParser) newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = parser.cfg.Delimiter
+	reader.Comment = parser.cfg.Comment
+	reader.LazyQuotes = parser.cfg.LazyQuotes
+	reader.FieldsPerRecord = -1 // handled by ragged rows policy
+
+	return reader
+}
+
+// next data row, the last rows are held back until newer rows are read so they can be skipped at the end of file
+func (parser *csvParser) readRecord() (*csvRecord, error) {
+	for len(parser.pending) <= parser.cfg.SkipTrailing {
+		offset := parser.getOffset()
+		columns, err := parser.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		parser.pending = append(parser.pending, csvRecord{columns, offset})
+	}
+
+	record := parser.pending[0]
+	parser.pending = parser.pending[1:]
+
+	return &record, nil
+}
+
+func (parser *csvParser) getOffset() int64 {
+	if parser.reader == nil {
+		return parser.baseOffset
+	}
+
+	return parser.baseOffset + parser.reader.InputOffset()
+}
+
+// byte offset of the next data row
+func (parser *csvParser) getNextOffset() int64 {
+	if len(parser.pending) > 0 {
+		return parser.pending[0].offset
+	}
+
+	return parser.getOffset()
+}
+
+func newCSVFailedRowsWriter(path string, fieldNames []string, cfg *config.Input) (*csvFailedRowsWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// error column of previous failed rows file can't be told apart by name if input file doesn't have
+	// header, so it's replaced instead of appended again on every pass
+	totalFields, truncate := len(fieldNames), false
+	if configured, exists := getConfiguredTotalFields(cfg); exists && !*cfg.HasHeader && configured < totalFields {
+		totalFields, truncate = configured, true
+	}
+
+	header, errorIndex := withErrorColumn(fieldNames[:totalFields])
+
+	writer := csv.NewWriter(f)
+	writer.Comma = cfg.Delimiter
+
+	// lines which are skipped by csv input config are written as placeholder, so the failed rows file has
+	// the same layout. Other input types are written as csv without skipped lines
+	leading, headerRow, trailing := 0, 1, 0
+	if InputType(cfg.Type) == InputTypeCSV {
+		leading, headerRow, trailing = cfg.SkipLines, cfg.HeaderRow, cfg.SkipTrailing
+	}
+
+	placeholders := leading
+	if *cfg.HasHeader {
+		placeholders += headerRow - 1
+	}
+
+	for i := 0; i < placeholders; i++ {
+		if err := writer.Write([]string{FailedRowsPlaceholder}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	// header is only written if input file has header
+	if *cfg.HasHeader {
+		if err := writer.Write(header); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return &csvFailedRowsWriter{f, writer, totalFields, errorIndex, truncate, trailing}, nil
+}
+
+func (writer *csvFailedRowsWriter) Write(row []string, errMsg string) error {
+	if writer.truncate && len(row) > writer.totalFields {
+		row = row[:writer.totalFields]
+	}
+
+	return writer.writer.Write(setErrorColumn(row, writer.totalFields, writer.errorIndex, errMsg))
+}
+
+func (writer *csvFailedRowsWriter) Close() error {
+	for i := 0; i < writer.trailing; i++ {
+		writer.writer.Write([]string{FailedRowsPlaceholder})
+	}
+
+	writer.writer.Flush()
+	if err := writer.writer.Error(); err != nil {
+		writer.file.Close()
+		return err
+	}
+
+	return writer.file.Close()
 }
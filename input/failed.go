@@ -0,0 +1,78 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+// text of lines which are written in place of lines skipped by input config, e.g. leading lines, rows above
+// header, and trailing lines, so failed rows file can be uploaded again with the same config
+const FailedRowsPlaceholder = "skipped"
+
+// write rejected rows in the original input format with an extra error column
+type FailedRowsWriter interface {
+	Write(row []string, errMsg string) error
+	Close() error
+}
+
+// rows of input which is not read from file are written as csv
+func (parser *Parser) NewFailedRowsWriter(path string) (FailedRowsWriter, error) {
+	switch InputType(parser.cfg.Input.Type) {
+	case InputTypeCSV, InputTypeMySQL, InputTypeRedis, InputTypeParquet, InputTypeXML, InputTypeGenerate:
+		return newCSVFailedRowsWriter(path, parser.fieldNames, &parser.cfg.Input)
+	case InputTypeJSON:
+		return newJSONFailedRowsWriter(path, parser.fieldNames, false)
+	case InputTypeNDJSON:
+		return newJSONFailedRowsWriter(path, parser.fieldNames, true)
+	case InputTypeXLSX:
+		return newXLSXFailedRowsWriter(path, parser.fieldNames, &parser.cfg.Input)
+	case InputTypeFixedWidth:
+		return newFixedWidthFailedRowsWriter(path, &parser.cfg.Input)
+	}
+
+	return nil, fmt.Errorf("failed rows file is not supported for input type: %s", parser.cfg.Input.Type)
+}
+
+// add error column to header, or reuse it if the input file is a failed rows file from previous run
+func withErrorColumn(fieldNames []string) (header []string, errorIndex int) {
+	last := len(fieldNames) - 1
+	if last >= 0 && fieldNames[last] == config.FailedRowsErrorColumn {
+		return fieldNames, last
+	}
+
+	header = append([]string{}, fieldNames...)
+	header = append(header, config.FailedRowsErrorColumn)
+
+	return header, len(header) - 1
+}
+
+// total columns which are referenced by input fields of config, false if fields are injected from input
+// file, so columns after the last field are not data of input file
+func getConfiguredTotalFields(cfg *config.Input) (total int, exists bool) {
+	if cfg.InjectFields || len(cfg.Fields) == 0 {
+		return 0, false
+	}
+
+	for i := range cfg.Fields {
+		if f := &cfg.Fields[i]; f.Order != nil && *f.Order+1 > total {
+			total = *f.Order + 1
+		}
+	}
+
+	return total, true
+}
+
+// place error message at error column, the original row is not modified. Error message of row which has
+// more columns than input file header is placed after its last column
+func setErrorColumn(row []string, totalFields, errorIndex int, errMsg string) []string {
+	if len(row) > totalFields {
+		errorIndex = len(row)
+	}
+
+	res := make([]string, errorIndex+1)
+	copy(res, row)
+	res[errorIndex] = errMsg
+
+	return res
+}
@@ -0,0 +1,152 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+func TestCSVFailedRowsWriterWithoutHeader(t *testing.T) {
+	order0, order1 := 0, 1
+	hasHeader := false
+
+	tests := []struct {
+		name       string
+		fields     []config.InputField
+		fieldNames []string
+		row        []string
+		want       string
+	}{
+		{
+			name:       "error column is appended",
+			fields:     []config.InputField{{Name: "a", Order: &order0}, {Name: "b", Order: &order1}},
+			fieldNames: []string{"col_1", "col_2"},
+			row:        []string{"1", "x"},
+			want:       "1,x,failed\n",
+		},
+		{
+			name:       "error column of previous pass is replaced",
+			fields:     []config.InputField{{Name: "a", Order: &order0}, {Name: "b", Order: &order1}},
+			fieldNames: []string{"col_1", "col_2", "col_3"},
+			row:        []string{"1", "x", "old error"},
+			want:       "1,x,failed\n",
+		},
+		{
+			name:       "injected fields keep every column",
+			fieldNames: []string{"col_1", "col_2", "col_3"},
+			row:        []string{"1", "x", "y"},
+			want:       "1,x,y,failed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "failed.csv")
+			cfg := &config.Input{Fields: tt.fields, InjectFields: len(tt.fields) == 0, HasHeader: &hasHeader, Delimiter: ','}
+
+			writer, err := newCSVFailedRowsWriter(path, tt.fieldNames, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := writer.Write(tt.row, "failed"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.ReplaceAll(string(data), "\r\n", "\n"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVFailedRowsRoundTripWithSkippedLines(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		data string
+		want [][]string
+	}{
+		{
+			name: "header",
+			cfg: `
+input:
+  skipLines: 1
+  headerRow: 2
+  skipTrailingLines: 1
+`,
+			data: "exported at 2024-01-01\nignored,row\nid,name\n1,a\n2,b\n3,c\ntotal,3\n",
+			want: [][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}},
+		},
+		{
+			name: "without header",
+			cfg: `
+input:
+  hasHeader: false
+  skipLines: 2
+  skipTrailingLines: 2
+  fields:
+    - name: id
+      order: 0
+    - name: name
+      order: 1
+`,
+			data: "exported at 2024-01-01\n\n1,a\n2,b\n3,c\ntotal,3\nend\n",
+			want: [][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := "batchSize: 10\n" + tt.cfg
+			parser := newTestParser(t, cfg, "data.csv", map[string]string{"data.csv": tt.data})
+			rows, _, batches := readAll(t, parser)
+			if len(rows) != 3 {
+				t.Fatalf("got %d rows of input file, want 3", len(rows))
+			}
+
+			path := filepath.Join(t.TempDir(), "data.failed.csv")
+			writer, err := parser.NewFailedRowsWriter(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, batch := range batches {
+				for i := range batch.Raw {
+					if err := writer.Write(batch.Raw[i], "bad"); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			failed, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// failed rows file is uploaded again with the same config
+			parser = newTestParser(t, cfg, "data.csv", map[string]string{"data.csv": string(failed)})
+			got, _, _ := readAll(t, parser)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got rows %q from failed rows file %q, want %q", got, failed, tt.want)
+			}
+		})
+	}
+}
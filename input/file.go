@@ -0,0 +1,345 @@
+package input
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ridwanadhip/universal-uploader/config"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+type (
+	Compression string
+)
+
+// known compressions
+const (
+	CompressionAuto Compression = "auto" // detected from file extension or magic bytes
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+	CompressionZip  Compression = "zip" // archive which contains the input file as an entry
+)
+
+// returned when reading from byte offset is requested for input which is decompressed or transcoded,
+// because offset of the decoded text is different from offset of input file
+var errSeekNotSupported = errors.New("seeking is not supported")
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+
+	magicGzip = []byte{0x1F, 0x8B}
+	magicZstd = []byte{0x28, 0xB5, 0x2F, 0xFD}
+	magicZip  = []byte{0x50, 0x4B, 0x03, 0x04}
+
+	compressionExtensions = map[string]Compression{
+		".gz":   CompressionGzip,
+		".gzip": CompressionGzip,
+		".zst":  CompressionZstd,
+		".zstd": CompressionZstd,
+		".zip":  CompressionZip,
+	}
+)
+
+// input file decompressed and decoded to UTF-8, byte order mark is removed
+type inputFile struct {
+	io.Reader
+	file        *os.File    // nil if input is read from stream
+	closers     []io.Closer // closed in reverse order
+	baseOffset  int64       // byte offset of input file where reader start reading
+	transformed bool        // if true then offset of reader is different from offset of input file
+}
+
+// open input file from byte offset. Encoding is detected from byte order mark if exists, otherwise the
+// encoding of input config is used
+func openInputFile(path string, cfg *config.Input, offset int64) (*inputFile, error) {
+	res, err := openDecompressedFile(path, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.decode(cfg, offset); err != nil {
+		res.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// read input from stream such as stdin, the stream is not closed and can't be seeked
+func openInputStream(r io.Reader, cfg *config.Input) (*inputFile, error) {
+	res := &inputFile{Reader: bufio.NewReader(r), transformed: true}
+
+	if err := res.decompress("", cfg); err != nil {
+		res.Close()
+		return nil, err
+	}
+
+	if err := res.decode(cfg, 0); err != nil {
+		res.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// open input file and decompress it if compressed, content is not decoded
+func openDecompressedFile(path string, cfg *config.Input) (*inputFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &inputFile{Reader: f, file: f, closers: []io.Closer{f}}
+	if err := res.decompress(path, cfg); err != nil {
+		res.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// decode content to UTF-8 and skip to byte offset
+func (f *inputFile) decode(cfg *config.Input, offset int64) error {
+	enc, err := getEncoding(cfg.Encoding)
+	if err != nil {
+		return err
+	}
+
+	bom, err := f.peek(len(bomUTF8))
+	if err != nil {
+		return err
+	}
+
+	var decoder transform.Transformer
+	switch {
+	case bytes.HasPrefix(bom, bomUTF8):
+		f.baseOffset = int64(len(bomUTF8))
+	case bytes.HasPrefix(bom, bomUTF16LE), bytes.HasPrefix(bom, bomUTF16BE):
+		decoder = unicode.BOMOverride(encoding.Nop.NewDecoder())
+	case enc != nil:
+		decoder = enc.NewDecoder()
+	}
+
+	if offset > 0 && (f.transformed || decoder != nil) {
+		return errSeekNotSupported
+	}
+
+	if offset == 0 {
+		offset = f.baseOffset
+	}
+
+	if err := f.skip(offset); err != nil {
+		return err
+	}
+
+	if decoder != nil {
+		f.Reader = transform.NewReader(f.Reader, decoder)
+		f.transformed = true
+	}
+
+	return nil
+}
+
+// name is used to detect compression from file extension, it's empty for stream
+func (f *inputFile) decompress(name string, cfg *config.Input) error {
+	compression, err := f.detectCompression(name, cfg)
+	if err != nil {
+		return err
+	}
+
+	var reader io.ReadCloser
+	switch compression {
+	case CompressionNone:
+		return nil
+	case CompressionGzip:
+		reader, err = gzip.NewReader(f.Reader)
+	case CompressionZstd:
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(f.Reader)
+		if err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	case CompressionZip:
+		if f.file == nil {
+			return fmt.Errorf("zip archive can't be read from stream")
+		}
+
+		reader, err = openZipEntry(f.file, cfg.Entry)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	f.Reader = bufio.NewReader(reader)
+	f.closers = append(f.closers, reader)
+	f.transformed = true
+
+	return nil
+}
+
+func (f *inputFile) Close() error {
+	var err error
+	for i := len(f.closers) - 1; i >= 0; i-- {
+		if closeErr := f.closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// first bytes of input file without consuming them
+func (f *inputFile) peek(n int) ([]byte, error) {
+	if buffered, ok := f.Reader.(*bufio.Reader); ok {
+		res, err := buffered.Peek(n)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+
+		return res, nil
+	}
+
+	res := make([]byte, n)
+	read, err := f.file.ReadAt(res, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return res[:read], nil
+}
+
+// skip bytes from the start of input file, the file is seeked directly if it's not decompressed
+func (f *inputFile) skip(offset int64) error {
+	f.baseOffset = offset
+
+	if buffered, ok := f.Reader.(*bufio.Reader); ok {
+		_, err := buffered.Discard(int(offset))
+		return err
+	}
+
+	_, err := f.file.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+// compression is detected from file extension, then from magic bytes. Zip magic bytes are ignored for
+// xlsx input because xlsx is a zip archive itself
+func (f *inputFile) detectCompression(name string, cfg *config.Input) (Compression, error) {
+	compression := Compression(strings.ToLower(cfg.Compression))
+	switch compression {
+	case "", CompressionAuto:
+	case CompressionNone, CompressionGzip, CompressionZstd, CompressionZip:
+		return compression, nil
+	default:
+		return "", fmt.Errorf("unknown input compression: %s", cfg.Compression)
+	}
+
+	if res, exists := compressionExtensions[strings.ToLower(filepath.Ext(name))]; exists {
+		return res, nil
+	}
+
+	magic, err := f.peek(len(magicZstd))
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		return CompressionGzip, nil
+	case bytes.HasPrefix(magic, magicZstd):
+		return CompressionZstd, nil
+	case bytes.HasPrefix(magic, magicZip) && InputType(cfg.Type) != InputTypeXLSX:
+		return CompressionZip, nil
+	}
+
+	return CompressionNone, nil
+}
+
+// entry is selected by name or glob pattern, which is matched against its full path or its base name.
+// Archive which only contains a single file doesn't need entry name
+func openZipEntry(f *os.File, pattern string) (io.ReadCloser, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(f, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	matches := []*zip.File{}
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		names = append(names, entry.Name)
+		if pattern == "" || matchZipEntry(pattern, entry.Name) {
+			matches = append(matches, entry)
+		}
+	}
+
+	sort.Strings(names)
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("zip entry '%s' is not found, available entries: %s", pattern, strings.Join(names, ", "))
+	}
+
+	if len(matches) > 1 {
+		matchedNames := []string{}
+		for _, entry := range matches {
+			matchedNames = append(matchedNames, entry.Name)
+		}
+
+		return nil, fmt.Errorf("more than one zip entry is found, choose one of them with input entry: %s", strings.Join(matchedNames, ", "))
+	}
+
+	return matches[0].Open()
+}
+
+func matchZipEntry(pattern string, name string) bool {
+	if matched, _ := path.Match(pattern, name); matched {
+		return true
+	}
+
+	matched, _ := path.Match(pattern, path.Base(name))
+	return matched
+}
+
+// nil if input is already UTF-8, so it doesn't need to be transcoded
+func getEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	enc, err := htmlindex.Get(strings.TrimSpace(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown input encoding: %s", name)
+	}
+
+	if enc == unicode.UTF8 {
+		return nil, nil
+	}
+
+	return enc, nil
+}
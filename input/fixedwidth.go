@@ -0,0 +1,361 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+// every field of fixed width input is read from its position in a line, positions are counted in characters
+// after the line is decoded to UTF-8. Empty lines are ignored
+type fixedWidthParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int
+	columns      []fixedWidthColumn
+	recordType   *fixedWidthColumn // nil if every line is a data row
+	file         *inputFile
+	reader       *bufio.Reader
+	offset       int64            // byte offset of the next line
+	pending      []fixedWidthLine // lines which are read ahead, so trailing lines can be skipped
+}
+
+// 0 based character positions, end is exclusive
+type fixedWidthColumn struct {
+	start int
+	end   int
+}
+
+type fixedWidthLine struct {
+	text   string
+	offset int64
+}
+
+// failed rows are written as fixed width lines followed by error message, so the error is ignored when the
+// file is uploaded again. Leading and trailing lines which are skipped by input config are written as
+// placeholder lines
+type fixedWidthFailedRowsWriter struct {
+	file       *os.File
+	writer     *bufio.Writer
+	columns    []fixedWidthColumn
+	recordType *fixedWidthColumn
+	recordText string // record type of written lines, so they are not skipped when uploaded again
+	width      int
+	trailing   int // placeholder lines which are written after the last row
+}
+
+func newFixedWidthParser(cfg *config.Input, batchSize int) (*fixedWidthParser, error) {
+	columns, recordType, err := getFixedWidthColumns(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fixedWidthParser{cfg: cfg, batchSize: batchSize, columns: columns, recordType: recordType}, nil
+}
+
+// fixed width input doesn't have header, field names are taken from input config
+func getFixedWidthColumns(cfg *config.Input) (columns []fixedWidthColumn, recordType *fixedWidthColumn, err error) {
+	if len(cfg.Fields) == 0 {
+		return nil, nil, fmt.Errorf("fields with start position are required for fixed width input")
+	}
+
+	for i := range cfg.Fields {
+		f := &cfg.Fields[i]
+
+		column, err := newFixedWidthColumn(f.Start, f.Width, f.End)
+		if err != nil {
+			return nil, nil, fmt.Errorf("input field '%s': %s", f.Name, err)
+		}
+
+		columns = append(columns, column)
+	}
+
+	if cfg.RecordType == nil {
+		return columns, nil, nil
+	}
+
+	column, err := newFixedWidthColumn(cfg.RecordType.Start, cfg.RecordType.Width, cfg.RecordType.End)
+	if err != nil {
+		return nil, nil, fmt.Errorf("input record type: %s", err)
+	}
+
+	if len(cfg.RecordType.Values) == 0 {
+		return nil, nil, fmt.Errorf("input record type: values are required")
+	}
+
+	return columns, &column, nil
+}
+
+func newFixedWidthColumn(start, width, end int) (fixedWidthColumn, error) {
+	if start < 1 {
+		return fixedWidthColumn{}, fmt.Errorf("start position must be greater than 0")
+	}
+
+	if width == 0 && end != 0 {
+		width = end - start + 1
+	}
+
+	if width < 1 {
+		return fixedWidthColumn{}, fmt.Errorf("width or end position after start position is required")
+	}
+
+	return fixedWidthColumn{start: start - 1, end: start - 1 + width}, nil
+}
+
+func (parser *fixedWidthParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
+	if parser.reader == nil {
+		if err := parser.open(path); err != nil {
+			return nil, false, err
+		}
+	}
+
+	data := [][]string{}
+	offsets := []int64{}
+	for len(data) < parser.batchSize {
+		line, err := parser.readLine()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		text := []rune(line.text)
+		if !parser.isDataRow(text) {
+			continue
+		}
+
+		row := []string{}
+		for _, column := range parser.columns {
+			row = append(row, column.read(text))
+		}
+
+		parser.currentIndex += 1
+		data = append(data, row)
+		offsets = append(offsets, line.offset)
+	}
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+	}
+
+	if !parser.file.transformed {
+		batch.Offsets = append(offsets, parser.getNextOffset())
+	}
+
+	// end of file
+	if len(batch.Data) == 0 {
+		parser.Close()
+		return nil, false, nil
+	}
+
+	return batch, true, nil
+}
+
+func (parser *fixedWidthParser) GetFieldNames(_ string) ([]string, error) {
+	fieldNames := []string{}
+	for i := range parser.cfg.Fields {
+		fieldNames = append(fieldNames, parser.cfg.Fields[i].Name)
+	}
+
+	return fieldNames, nil
+}
+
+// continue reading from byte offset of a data row, leading lines are already skipped
+func (parser *fixedWidthParser) SeekRow(path string, offset int64, index int) error {
+	parser.Close()
+
+	f, err := openInputFile(path, parser.cfg, offset)
+	if err != nil {
+		return err
+	}
+
+	parser.file = f
+	parser.reader = bufio.NewReader(f)
+	parser.offset = offset
+	parser.currentIndex = index
+
+	return nil
+}
+
+func (parser *fixedWidthParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *fixedWidthParser) Close() {
+	if parser.reader != nil {
+		parser.file.Close()
+		parser.file = nil
+		parser.reader = nil
+		parser.pending = nil
+		parser.offset = 0
+		parser.currentIndex = 0
+	}
+}
+
+// open file and skip leading lines
+func (parser *fixedWidthParser) open(path string) error {
+	f, err := openInputFile(path, parser.cfg, 0)
+	if err != nil {
+		return err
+	}
+
+	parser.file = f
+	parser.reader = bufio.NewReader(f)
+	parser.offset = f.baseOffset
+
+	for i := 0; i < parser.cfg.SkipLines; i++ {
+		line, err := parser.reader.ReadString('\n')
+		parser.offset += int64(len(line))
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			parser.Close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// next non empty line, the last lines are held back until newer lines are read so they can be skipped at
+// the end of file
+func (parser *fixedWidthParser) readLine() (*fixedWidthLine, error) {
+	for len(parser.pending) <= parser.cfg.SkipTrailing {
+		offset := parser.offset
+		text, err := parser.reader.ReadString('\n')
+		parser.offset += int64(len(text))
+
+		if err != nil && (err != io.EOF || text == "") {
+			return nil, err
+		}
+
+		text = strings.TrimRight(text, "\r\n")
+		if text != "" {
+			parser.pending = append(parser.pending, fixedWidthLine{text, offset})
+		}
+	}
+
+	line := parser.pending[0]
+	parser.pending = parser.pending[1:]
+
+	return &line, nil
+}
+
+// byte offset of the next line
+func (parser *fixedWidthParser) getNextOffset() int64 {
+	if len(parser.pending) > 0 {
+		return parser.pending[0].offset
+	}
+
+	return parser.offset
+}
+
+func (parser *fixedWidthParser) isDataRow(text []rune) bool {
+	if parser.recordType == nil {
+		return true
+	}
+
+	recordType := parser.recordType.read(text)
+	for _, val := range parser.cfg.RecordType.Values {
+		if recordType == val {
+			return true
+		}
+	}
+
+	return false
+}
+
+// part of line which is shorter than the column is read as is, or as empty string if line ends before the column
+func (column fixedWidthColumn) read(text []rune) string {
+	if column.start >= len(text) {
+		return ""
+	}
+
+	if column.end > len(text) {
+		return string(text[column.start:])
+	}
+
+	return string(text[column.start:column.end])
+}
+
+// place value at the column position, value which is longer than the column is truncated
+func (column fixedWidthColumn) write(line []rune, value string) {
+	runes := []rune(value)
+	for i := column.start; i < column.end && i-column.start < len(runes); i++ {
+		line[i] = runes[i-column.start]
+	}
+}
+
+func newFixedWidthFailedRowsWriter(path string, cfg *config.Input) (*fixedWidthFailedRowsWriter, error) {
+	columns, recordType, err := getFixedWidthColumns(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &fixedWidthFailedRowsWriter{file: f, writer: bufio.NewWriter(f), columns: columns, recordType: recordType, trailing: cfg.SkipTrailing}
+	if recordType != nil {
+		writer.recordText = cfg.RecordType.Values[0]
+		writer.width = recordType.end
+	}
+
+	for _, column := range columns {
+		if column.end > writer.width {
+			writer.width = column.end
+		}
+	}
+
+	for i := 0; i < cfg.SkipLines; i++ {
+		if _, err := writer.writer.WriteString(FailedRowsPlaceholder + "\n"); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return writer, nil
+}
+
+func (writer *fixedWidthFailedRowsWriter) Write(row []string, errMsg string) error {
+	line := []rune(strings.Repeat(" ", writer.width))
+	if writer.recordType != nil {
+		writer.recordType.write(line, writer.recordText)
+	}
+
+	for i, column := range writer.columns {
+		if i < len(row) {
+			column.write(line, row[i])
+		}
+	}
+
+	errMsg = strings.NewReplacer("\r", " ", "\n", " ").Replace(errMsg)
+	_, err := writer.writer.WriteString(string(line) + " " + errMsg + "\n")
+
+	return err
+}
+
+func (writer *fixedWidthFailedRowsWriter) Close() error {
+	for i := 0; i < writer.trailing; i++ {
+		writer.writer.WriteString(FailedRowsPlaceholder + "\n")
+	}
+
+	if err := writer.writer.Flush(); err != nil {
+		writer.file.Close()
+		return err
+	}
+
+	return writer.file.Close()
+}
@@ -0,0 +1,137 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const fixedWidthTestConfig = `
+batchSize: 2
+input:
+  type: fixedwidth
+  trimSpaces: true
+  recordType:
+    start: 1
+    width: 1
+    values: [D]
+  fields:
+    - name: account
+      start: 2
+      width: 5
+    - name: name
+      start: 7
+      end: 12
+    - name: amount
+      start: 13
+      width: 6
+`
+
+func TestFixedWidthInput(t *testing.T) {
+	data := "" +
+		"H20240101\n" +
+		"D00001José  000120\n" +
+		"\n" +
+		"D00002Ann\n" +
+		"D00003Bob  X001500\n" +
+		"T000003\n"
+
+	parser := newTestParser(t, fixedWidthTestConfig, "data.txt", map[string]string{"data.txt": data})
+	rows, lines, _ := readAll(t, parser)
+
+	// positions are counted in characters, and field after the end of a short line is empty
+	wantRows := [][]string{
+		{"00001", "José", "000120"},
+		{"00002", "Ann", ""},
+		{"00003", "Bob  X", "001500"},
+	}
+
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("got rows %q, want %q", rows, wantRows)
+	}
+
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+}
+
+const fixedWidthSkipTestConfig = `
+batchSize: 10
+input:
+  type: fixedwidth
+  skipLines: 1
+  skipTrailingLines: 1
+  fields:
+    - name: account
+      start: 1
+      width: 5
+    - name: amount
+      start: 6
+      width: 6
+`
+
+func TestFixedWidthFailedRowsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    string
+		data   string
+		failed string
+	}{
+		{
+			name:   "record type",
+			cfg:    fixedWidthTestConfig,
+			data:   "D00001José  000120\nD00002Ann   000300\n",
+			failed: "D00001José  000120 invalid amount\nD00002Ann   000300 invalid amount\n",
+		},
+		{
+			name:   "skipped header and trailer lines",
+			cfg:    fixedWidthSkipTestConfig,
+			data:   "HDR20240101\n00001000120\n00002000300\n00003000450\nTRL000003\n",
+			failed: "skipped\n00001000120 invalid amount\n00002000300 invalid amount\n00003000450 invalid amount\nskipped\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newTestParser(t, tt.cfg, "data.txt", map[string]string{"data.txt": tt.data})
+			rows, _, batches := readAll(t, parser)
+
+			path := filepath.Join(t.TempDir(), "data.failed.txt")
+			writer, err := parser.NewFailedRowsWriter(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, batch := range batches {
+				for i := range batch.Raw {
+					if err := writer.Write(batch.Raw[i], "invalid amount"); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			failed, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(failed) != tt.failed {
+				t.Errorf("got failed rows file %q, want %q", failed, tt.failed)
+			}
+
+			// error message is after the last column, and skipped lines are placeholders, so the file is read
+			// again with the same config
+			reuploaded := newTestParser(t, tt.cfg, "data.failed.txt", map[string]string{"data.failed.txt": string(failed)})
+			got, _, _ := readAll(t, reuploaded)
+
+			if !reflect.DeepEqual(got, rows) {
+				t.Errorf("got rows %q, want %q", got, rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package input
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+	"github.com/ridwanadhip/universal-uploader/util"
+)
+
+// layout of date generator if format is not set
+const DefaultGeneratorDateFormat = "2006-01-02"
+
+// unbounded repetition of pattern, e.g. * and +, is repeated at most this count
+const maxPatternRepeat = 8
+
+// printable ascii characters, generated by any character and preferred by character class of pattern
+var printableRunes = []rune{0x20, 0x7e}
+
+// rows are generated from generator rule of every input field. Random values are reproducible if seed is
+// set, so resumed upload generates the same rows before skipping the uploaded ones
+type generateParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int
+	generators   []valueGenerator
+	rng          *rand.Rand // nil before the first batch
+}
+
+// value of a row by its 0 based index
+type valueGenerator func(rng *rand.Rand, index int) string
+
+func newGenerateParser(cfg *config.Input, batchSize int) (*generateParser, error) {
+	if cfg.Rows < 1 {
+		return nil, fmt.Errorf("rows must be greater than 0 for generate input")
+	}
+
+	if len(cfg.Fields) == 0 {
+		return nil, fmt.Errorf("fields with generator are required for generate input")
+	}
+
+	generators := []valueGenerator{}
+	for i := range cfg.Fields {
+		f := &cfg.Fields[i]
+		if f.Generator == nil {
+			return nil, fmt.Errorf("input field '%s': generator is required", f.Name)
+		}
+
+		generator, err := newValueGenerator(f.Generator)
+		if err != nil {
+			return nil, fmt.Errorf("input field '%s': %s", f.Name, err)
+		}
+
+		generators = append(generators, generator)
+	}
+
+	return &generateParser{cfg: cfg, batchSize: batchSize, generators: generators}, nil
+}
+
+func (parser *generateParser) NextBatch(_ string) (batch *Batch, exists bool, err error) {
+	if parser.rng == nil {
+		seed := time.Now().UnixNano()
+		if parser.cfg.Seed != nil {
+			seed = *parser.cfg.Seed
+		}
+
+		parser.rng = rand.New(rand.NewSource(seed))
+	}
+
+	total := util.MinInt(parser.batchSize, parser.cfg.Rows-parser.currentIndex)
+
+	// all rows are generated
+	if total <= 0 {
+		parser.Close()
+		return nil, false, nil
+	}
+
+	data := [][]string{}
+	for i := 0; i < total; i++ {
+		row := make([]string, len(parser.generators))
+		for j, generator := range parser.generators {
+			row[j] = generator(parser.rng, parser.currentIndex)
+		}
+
+		parser.currentIndex += 1
+		data = append(data, row)
+	}
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+	}
+
+	return batch, true, nil
+}
+
+func (parser *generateParser) GetFieldNames(_ string) ([]string, error) {
+	fieldNames := []string{}
+	for i := range parser.cfg.Fields {
+		fieldNames = append(fieldNames, parser.cfg.Fields[i].Name)
+	}
+
+	return fieldNames, nil
+}
+
+func (parser *generateParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *generateParser) Close() {
+	parser.rng = nil
+	parser.currentIndex = 0
+}
+
+func newValueGenerator(g *config.Generator) (valueGenerator, error) {
+	switch g.Type {
+	case config.GeneratorSequence:
+		return newSequenceGenerator(g), nil
+	case config.GeneratorInt:
+		return newNumberGenerator(g, 0)
+	case config.GeneratorDecimal:
+		if g.Scale < 0 {
+			return nil, fmt.Errorf("decimal generator scale must not be negative")
+		}
+
+		return newNumberGenerator(g, g.Scale)
+	case config.GeneratorUUID:
+		return generateUUID, nil
+	case config.GeneratorPick:
+		if len(g.Values) == 0 {
+			return nil, fmt.Errorf("values are required for pick generator")
+		}
+
+		return func(rng *rand.Rand, _ int) string {
+			return g.Values[rng.Intn(len(g.Values))]
+		}, nil
+	case config.GeneratorDate:
+		return newDateGenerator(g)
+	case config.GeneratorPattern:
+		return newPatternGenerator(g)
+	}
+
+	return nil, fmt.Errorf("unknown generator type: %s", g.Type)
+}
+
+func newSequenceGenerator(g *config.Generator) valueGenerator {
+	step := g.Step
+	if step == 0 {
+		step = 1
+	}
+
+	return func(_ *rand.Rand, index int) string {
+		val := g.Start + int64(index)*step
+		if g.Format != "" {
+			return fmt.Sprintf(g.Format, val)
+		}
+
+		return strconv.FormatInt(val, 10)
+	}
+}
+
+// numbers are generated as unscaled integer, so every value between min and max has the same chance
+func newNumberGenerator(g *config.Generator, scale int) (valueGenerator, error) {
+	min, err := parseScaledNumber(g.Min, scale)
+	if err != nil {
+		return nil, fmt.Errorf("generator min: %s", err)
+	}
+
+	max, err := parseScaledNumber(g.Max, scale)
+	if err != nil {
+		return nil, fmt.Errorf("generator max: %s", err)
+	}
+
+	if max.Cmp(min) < 0 {
+		return nil, fmt.Errorf("generator max must not be less than min")
+	}
+
+	span := new(big.Int).Sub(max, min)
+	span.Add(span, big.NewInt(1))
+
+	return func(rng *rand.Rand, _ int) string {
+		val := new(big.Int).Rand(rng, span)
+		return formatUnscaledDecimal(val.Add(val, min), scale)
+	}, nil
+}
+
+// digits after the scale are truncated
+func parseScaledNumber(text string, scale int) (*big.Int, error) {
+	if text == "" {
+		return nil, fmt.Errorf("value is required")
+	}
+
+	val, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", text)
+	}
+
+	val.Mul(val, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+
+	return new(big.Int).Quo(val.Num(), val.Denom()), nil
+}
+
+// random version 4 uuid, generated from seeded source instead of crypto source so it's reproducible
+func generateUUID(rng *rand.Rand, _ int) string {
+	b := make([]byte, 16)
+	rng.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func newDateGenerator(g *config.Generator) (valueGenerator, error) {
+	layout := g.Format
+	if layout == "" {
+		layout = DefaultGeneratorDateFormat
+	}
+
+	min, err := time.Parse(layout, g.Min)
+	if err != nil {
+		return nil, fmt.Errorf("generator min: %s", err)
+	}
+
+	max, err := time.Parse(layout, g.Max)
+	if err != nil {
+		return nil, fmt.Errorf("generator max: %s", err)
+	}
+
+	if max.Before(min) {
+		return nil, fmt.Errorf("generator max must not be before min")
+	}
+
+	unit := getDateUnit(min, layout)
+	total := int64(max.Sub(min)/unit) + 1
+
+	return func(rng *rand.Rand, _ int) string {
+		return min.Add(time.Duration(rng.Int63n(total)) * unit).Format(layout)
+	}, nil
+}
+
+// smallest unit which is kept by the layout, so date only layout generates whole days and max date is
+// generated as often as the others
+func getDateUnit(t time.Time, layout string) time.Duration {
+	for _, unit := range []time.Duration{time.Second, time.Minute, time.Hour} {
+		next := t.Add(unit)
+		if parsed, err := time.Parse(layout, next.Format(layout)); err == nil && parsed.Equal(next) {
+			return unit
+		}
+	}
+
+	return 24 * time.Hour
+}
+
+// value which matches the pattern, anchors such as ^ and $ don't generate any character
+func newPatternGenerator(g *config.Generator) (valueGenerator, error) {
+	if g.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required for pattern generator")
+	}
+
+	re, err := syntax.Parse(g.Pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generator pattern: %s", err)
+	}
+
+	return func(rng *rand.Rand, _ int) string {
+		var sb strings.Builder
+		generatePattern(rng, re, &sb)
+
+		return sb.String()
+	}, nil
+}
+
+func generatePattern(rng *rand.Rand, re *syntax.Regexp, sb *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			sb.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		if len(re.Rune) > 0 {
+			sb.WriteRune(pickClassRune(rng, re.Rune))
+		}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteRune(pickClassRune(rng, printableRunes))
+	case syntax.OpCapture:
+		generatePattern(rng, re.Sub[0], sb)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			generatePattern(rng, sub, sb)
+		}
+	case syntax.OpAlternate:
+		generatePattern(rng, re.Sub[rng.Intn(len(re.Sub))], sb)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := getPatternRepeat(re)
+		count := min + rng.Intn(max-min+1)
+		for i := 0; i < count; i++ {
+			generatePattern(rng, re.Sub[0], sb)
+		}
+	}
+}
+
+func getPatternRepeat(re *syntax.Regexp) (min int, max int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, maxPatternRepeat
+	case syntax.OpPlus:
+		return 1, maxPatternRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	}
+
+	if re.Max < 0 {
+		return re.Min, re.Min + maxPatternRepeat
+	}
+
+	return re.Min, re.Max
+}
+
+// ranges are pairs of first and last rune. Printable ascii characters of the class are preferred, so
+// negated class such as [^,] doesn't generate control characters
+func pickClassRune(rng *rand.Rand, ranges []rune) rune {
+	printable := []rune{}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo := ranges[i]
+		if lo < printableRunes[0] {
+			lo = printableRunes[0]
+		}
+
+		hi := ranges[i+1]
+		if hi > printableRunes[1] {
+			hi = printableRunes[1]
+		}
+
+		if lo <= hi {
+			printable = append(printable, lo, hi)
+		}
+	}
+
+	if len(printable) > 0 {
+		ranges = printable
+	}
+
+	total := 0
+	for i := 0; i+1 < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+
+	n := rng.Intn(total)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+
+		n -= size
+	}
+
+	return ranges[0]
+}
@@ -0,0 +1,93 @@
+package input
+
+import (
+	"math/rand"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+const generateTestConfig = `
+batchSize: 3
+input:
+  type: generate
+  rows: 7
+  seed: 42
+  fields:
+    - name: id
+      generator: {type: sequence, start: 1, format: "INV%03d"}
+    - name: quantity
+      generator: {type: int, min: 1, max: 10}
+    - name: amount
+      generator: {type: decimal, min: 0.01, max: 5000, scale: 2}
+    - name: token
+      generator: {type: uuid}
+    - name: status
+      generator: {type: pick, values: [new, paid, cancelled]}
+    - name: created_at
+      generator: {type: date, min: "2024-01-01", max: "2024-12-31"}
+    - name: code
+      generator: {type: pattern, pattern: '[A-Z]{3}-\d{4}'}
+`
+
+func TestGenerateInputSameSeed(t *testing.T) {
+	first, _, _ := readAll(t, newTestParser(t, generateTestConfig, "", nil))
+	second, _, _ := readAll(t, newTestParser(t, generateTestConfig, "", nil))
+
+	if len(first) != 7 {
+		t.Fatalf("got %d rows, want 7", len(first))
+	}
+
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("rows of the same seed are different:\n%q\n%q", first, second)
+	}
+
+	if first[0][0] != "INV001" || first[6][0] != "INV007" {
+		t.Errorf("got sequence %s to %s, want INV001 to INV007", first[0][0], first[6][0])
+	}
+
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(`^INV\d{3}$`),
+		regexp.MustCompile(`^([1-9]|10)$`),
+		regexp.MustCompile(`^\d{1,4}\.\d{2}$`),
+		regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		regexp.MustCompile(`^(new|paid|cancelled)$`),
+		regexp.MustCompile(`^2024-\d{2}-\d{2}$`),
+		regexp.MustCompile(`^[A-Z]{3}-\d{4}$`),
+	}
+
+	for _, row := range first {
+		for i, re := range patterns {
+			if !re.MatchString(row[i]) {
+				t.Errorf("value %q doesn't match %s", row[i], re)
+			}
+		}
+	}
+}
+
+func TestPatternGenerator(t *testing.T) {
+	patterns := []string{
+		`[A-Z]{3}-\d{4}`,
+		`^(foo|bar)_\w+$`,
+		`[^,]{2,5}`,
+		`a.b?c*`,
+		`(ab)+[x-z]{0,3}`,
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, pattern := range patterns {
+		generator, err := newPatternGenerator(&config.Generator{Type: config.GeneratorPattern, Pattern: pattern})
+		if err != nil {
+			t.Fatalf("pattern %s: %s", pattern, err)
+		}
+
+		re := regexp.MustCompile(`^(?:` + pattern + `)$`)
+		for i := 0; i < 200; i++ {
+			if val := generator(rng, i); !re.MatchString(val) {
+				t.Errorf("value %q doesn't match pattern %s", val, pattern)
+			}
+		}
+	}
+}
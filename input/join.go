@@ -0,0 +1,312 @@
+package input
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+// name pattern of temporary file of disk join index
+const JoinIndexFilePattern = "universal-uploader-join-*"
+
+// rows of secondary input are indexed by key before the first input row is read, then every input row
+// is joined with the row which key is equal to value of its join field. Both sides are compared after
+// surrounding spaces are removed regardless of trimSpaces of their config, otherwise the comparison is
+// exact. Empty key never matches
+type joiner struct {
+	join    *config.Join
+	columns []string // field names of secondary input, prefixed by join alias
+	onIndex int      // position of join field in input row
+	index   joinIndex
+}
+
+// rows of secondary input by their key
+type joinIndex interface {
+	Add(key string, row joinRow) (added bool, err error) // false if key already exists
+	Get(key string) (row *joinRow, err error)            // nil if key doesn't exist
+	Close()
+}
+
+type joinRow struct {
+	Values []string `json:"v"`
+	Nulls  []bool   `json:"n,omitempty"`
+}
+
+type memoryJoinIndex struct {
+	rows map[string]joinRow
+}
+
+// only hash of key and position of row in temporary file are kept in memory, so secondary input which
+// is larger than memory can be joined. Keys are compared after the row is read back
+type diskJoinIndex struct {
+	file    *os.File
+	writer  *bufio.Writer
+	offset  int64
+	flushed bool
+	entries map[uint64][]diskJoinEntry
+}
+
+type diskJoinEntry struct {
+	offset int64
+	size   int
+}
+
+// row of disk index, key is written so rows of keys which have the same hash can be told apart
+type diskJoinRow struct {
+	Key string `json:"k"`
+	joinRow
+}
+
+// join field is matched by name of input field, which is the column name of input file
+func newJoiner(join *config.Join, fieldNames []string) (*joiner, error) {
+	res := &joiner{join: join, onIndex: -1}
+	for i, name := range fieldNames {
+		if name == join.On {
+			res.onIndex = i
+			break
+		}
+	}
+
+	if res.onIndex < 0 {
+		return nil, fmt.Errorf("join '%s': field '%s' is not found in input", join.Alias, join.On)
+	}
+
+	if err := res.buildIndex(); err != nil {
+		res.Close()
+		return nil, fmt.Errorf("join '%s': %s", join.Alias, err)
+	}
+
+	return res, nil
+}
+
+func (joiner *joiner) buildIndex() error {
+	switch joiner.join.Index {
+	case config.JoinIndexDisk:
+		index, err := newDiskJoinIndex()
+		if err != nil {
+			return err
+		}
+
+		joiner.index = index
+	default:
+		joiner.index = &memoryJoinIndex{rows: map[string]joinRow{}}
+	}
+
+	parser, err := NewParser(joiner.join.Config)
+	if err != nil {
+		return err
+	}
+
+	defer parser.Close()
+
+	if err := parser.Validate(); err != nil {
+		return err
+	}
+
+	fields := joiner.join.Config.Input.Fields
+	keyIndex := -1
+	for i := range fields {
+		joiner.columns = append(joiner.columns, joiner.join.Alias+config.JoinAliasSeparator+fields[i].Name)
+		if fields[i].Name == joiner.join.Key {
+			keyIndex = i
+		}
+	}
+
+	if keyIndex < 0 {
+		return fmt.Errorf("key '%s' is not found in secondary input", joiner.join.Key)
+	}
+
+	for {
+		batch, exists, err := parser.NextBatch()
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return nil
+		}
+
+		for i, values := range batch.Data {
+			key := strings.TrimSpace(values[keyIndex])
+			if key == "" {
+				continue
+			}
+
+			row := joinRow{Values: values}
+			if batch.Nulls != nil {
+				row.Nulls = batch.Nulls[i]
+			}
+
+			added, err := joiner.index.Add(key, row)
+			if err != nil {
+				return err
+			}
+
+			if !added {
+				return fmt.Errorf("line %d: key '%s' is used more than once in secondary input", batch.RowIndex(i)+1, key)
+			}
+		}
+	}
+}
+
+// columns of secondary input which are appended to input row, they are null if there is no matching
+// row in left join. Returns nil if there is no matching row in inner join
+func (joiner *joiner) joinRow(row []string) (values []string, nulls []bool, err error) {
+	var match *joinRow
+	if key := strings.TrimSpace(row[joiner.onIndex]); key != "" {
+		match, err = joiner.index.Get(key)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if match == nil && joiner.join.Type == config.JoinTypeInner {
+		return nil, nil, nil
+	}
+
+	if match == nil {
+		nulls = make([]bool, len(joiner.columns))
+		for i := range nulls {
+			nulls[i] = true
+		}
+
+		return make([]string, len(joiner.columns)), nulls, nil
+	}
+
+	nulls = make([]bool, len(joiner.columns))
+	copy(nulls, match.Nulls)
+
+	return match.Values, nulls, nil
+}
+
+func (joiner *joiner) Close() {
+	if joiner.index != nil {
+		joiner.index.Close()
+	}
+}
+
+func (index *memoryJoinIndex) Add(key string, row joinRow) (bool, error) {
+	if _, exists := index.rows[key]; exists {
+		return false, nil
+	}
+
+	index.rows[key] = row
+	return true, nil
+}
+
+func (index *memoryJoinIndex) Get(key string) (*joinRow, error) {
+	row, exists := index.rows[key]
+	if !exists {
+		return nil, nil
+	}
+
+	return &row, nil
+}
+
+func (index *memoryJoinIndex) Close() {
+	index.rows = nil
+}
+
+func newDiskJoinIndex() (*diskJoinIndex, error) {
+	f, err := os.CreateTemp("", JoinIndexFilePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diskJoinIndex{file: f, writer: bufio.NewWriter(f), entries: map[uint64][]diskJoinEntry{}}, nil
+}
+
+func (index *diskJoinIndex) Add(key string, row joinRow) (bool, error) {
+	existing, err := index.Get(key)
+	if err != nil || existing != nil {
+		return false, err
+	}
+
+	data, err := json.Marshal(diskJoinRow{Key: key, joinRow: row})
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := index.writer.Write(data); err != nil {
+		return false, err
+	}
+
+	hash := hashJoinKey(key)
+	index.entries[hash] = append(index.entries[hash], diskJoinEntry{offset: index.offset, size: len(data)})
+	index.offset += int64(len(data))
+	index.flushed = false
+
+	return true, nil
+}
+
+func (index *diskJoinIndex) Get(key string) (*joinRow, error) {
+	entries := index.entries[hashJoinKey(key)]
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// rows are buffered while the index is built
+	if !index.flushed {
+		if err := index.writer.Flush(); err != nil {
+			return nil, err
+		}
+
+		index.flushed = true
+	}
+
+	for _, entry := range entries {
+		data := make([]byte, entry.size)
+		if _, err := index.file.ReadAt(data, entry.offset); err != nil {
+			return nil, err
+		}
+
+		var row diskJoinRow
+		if err := json.Unmarshal(data, &row); err != nil {
+			return nil, err
+		}
+
+		if row.Key == key {
+			return &row.joinRow, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (index *diskJoinIndex) Close() {
+	index.file.Close()
+	os.Remove(index.file.Name())
+	index.entries = nil
+}
+
+func hashJoinKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	return h.Sum64()
+}
+
+// columns of every secondary input for input row, missing is the join which doesn't have matching row
+// for inner join
+func (parser *Parser) joinRow(row []string) (values []string, nulls []bool, missing *joiner, err error) {
+	for _, joiner := range parser.joiners {
+		joinedValues, joinedNulls, err := joiner.joinRow(row)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("join '%s': %s", joiner.join.Alias, err)
+		}
+
+		if joinedValues == nil {
+			return nil, nil, joiner, nil
+		}
+
+		values = append(values, joinedValues...)
+		nulls = append(nulls, joinedNulls...)
+	}
+
+	return values, nulls, nil, nil
+}
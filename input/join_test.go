@@ -0,0 +1,177 @@
+package input
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const joinTestConfig = `
+batchSize: 2
+input:
+  joins:
+    - alias: codes
+      path: $DIR/codes.csv
+      key: old_code
+      on: code
+      type: %TYPE%
+      index: %INDEX%
+      input:
+        delimiter: ";"
+        trimSpaces: true
+targets:
+  - type: mysql
+    name: db
+    dataName: products
+    fields:
+      - name: id
+      - name: code
+        value: ^codes.new_code^
+`
+
+func newJoinTestConfig(joinType, index string) string {
+	return strings.NewReplacer("%TYPE%", joinType, "%INDEX%", index).Replace(joinTestConfig)
+}
+
+func TestJoin(t *testing.T) {
+	input := "id,code\n1,A\n2, B \n3,Z\n4,\n5,A\n"
+	codes := "old_code;new_code\nA;X\n  B ;Y\n"
+
+	tests := []struct {
+		joinType     string
+		index        string
+		wantRows     [][]string
+		wantLines    []int
+		wantNulls    [][]bool
+		wantRejected []int
+	}{
+		{
+			joinType:     "inner",
+			index:        "memory",
+			wantRows:     [][]string{{"1", "A", "X"}, {"2", " B ", "Y"}, {"5", "A", "X"}},
+			wantLines:    []int{1, 2, 5},
+			wantNulls:    [][]bool{{false, false, false}, {false, false, false}, {false, false, false}},
+			wantRejected: []int{3, 4},
+		},
+		{
+			joinType:     "inner",
+			index:        "disk",
+			wantRows:     [][]string{{"1", "A", "X"}, {"2", " B ", "Y"}, {"5", "A", "X"}},
+			wantLines:    []int{1, 2, 5},
+			wantNulls:    [][]bool{{false, false, false}, {false, false, false}, {false, false, false}},
+			wantRejected: []int{3, 4},
+		},
+		{
+			joinType:  "left",
+			index:     "memory",
+			wantRows:  [][]string{{"1", "A", "X"}, {"2", " B ", "Y"}, {"3", "Z", ""}, {"4", "", ""}, {"5", "A", "X"}},
+			wantLines: []int{1, 2, 3, 4, 5},
+			wantNulls: [][]bool{{false, false, false}, {false, false, false}, {false, false, true}, {false, false, true}, {false, false, false}},
+		},
+		{
+			joinType:  "left",
+			index:     "disk",
+			wantRows:  [][]string{{"1", "A", "X"}, {"2", " B ", "Y"}, {"3", "Z", ""}, {"4", "", ""}, {"5", "A", "X"}},
+			wantLines: []int{1, 2, 3, 4, 5},
+			wantNulls: [][]bool{{false, false, false}, {false, false, false}, {false, false, true}, {false, false, true}, {false, false, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.joinType+"/"+tt.index, func(t *testing.T) {
+			files := map[string]string{"data.csv": input, "codes.csv": codes}
+			parser := newTestParser(t, newJoinTestConfig(tt.joinType, tt.index), "data.csv", files)
+
+			rows, lines, batches := readAll(t, parser)
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("got rows %q, want %q", rows, tt.wantRows)
+			}
+
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("got lines %v, want %v", lines, tt.wantLines)
+			}
+
+			nulls := [][]bool{}
+			rejected := []int{}
+			for _, batch := range batches {
+				nulls = append(nulls, batch.Nulls...)
+				for _, row := range batch.Rejected {
+					rejected = append(rejected, row.Line)
+				}
+			}
+
+			if !reflect.DeepEqual(nulls, tt.wantNulls) {
+				t.Errorf("got nulls %v, want %v", nulls, tt.wantNulls)
+			}
+
+			if len(rejected) == 0 {
+				rejected = nil
+			}
+
+			if !reflect.DeepEqual(rejected, tt.wantRejected) {
+				t.Errorf("got rejected lines %v, want %v", rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestJoinDuplicateKey(t *testing.T) {
+	for _, index := range []string{"memory", "disk"} {
+		t.Run(index, func(t *testing.T) {
+			files := map[string]string{"data.csv": "id,code\n1,A\n", "codes.csv": "old_code;new_code\nA;X\nB;Y\n A ;Z\n"}
+
+			_, err := openTestParser(t, newJoinTestConfig("inner", index), "data.csv", files)
+			if err == nil || !strings.Contains(err.Error(), "line 3: key 'A' is used more than once") {
+				t.Errorf("expected duplicate key error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiskJoinIndexHashCollision(t *testing.T) {
+	index, err := newDiskJoinIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer index.Close()
+
+	if _, err := index.Add("a", joinRow{Values: []string{"a", "1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend "b" has the same hash as "a", so rows of both keys are in the same bucket
+	index.entries[hashJoinKey("b")] = append([]diskJoinEntry{}, index.entries[hashJoinKey("a")]...)
+
+	row, err := index.Get("b")
+	if err != nil || row != nil {
+		t.Fatalf("expected no row for colliding key, got %v, %v", row, err)
+	}
+
+	added, err := index.Add("b", joinRow{Values: []string{"b", "2"}, Nulls: []bool{false, true}})
+	if err != nil || !added {
+		t.Fatalf("expected colliding key to be added, got %v, %v", added, err)
+	}
+
+	if total := len(index.entries[hashJoinKey("b")]); total != 2 {
+		t.Fatalf("expected 2 rows in bucket, got %d", total)
+	}
+
+	for key, want := range map[string]joinRow{
+		"a": {Values: []string{"a", "1"}},
+		"b": {Values: []string{"b", "2"}, Nulls: []bool{false, true}},
+	} {
+		row, err := index.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if row == nil || !reflect.DeepEqual(*row, want) {
+			t.Errorf("key %s: got %+v, want %+v", key, row, want)
+		}
+	}
+
+	if added, _ := index.Add("a", joinRow{Values: []string{"a", "3"}}); added {
+		t.Errorf("expected existing key not to be added")
+	}
+}
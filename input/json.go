@@ -0,0 +1,485 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+// nested object is flattened, so its fields are referenced by dotted path, e.g. user.address.city
+const JSONPathSeparator = "."
+
+// read a top-level array of objects, or one object per line if lines is true. Records are
+// streamed one by one, so the whole file is never loaded to memory
+type jsonParser struct {
+	cfg          *config.Input
+	batchSize    int
+	lines        bool
+	currentIndex int
+	fieldIndex   map[string]int // position of flattened field name in row, populated by GetFieldNames
+	offset       int64          // byte offset of the next line, only for lines mode
+	file         *inputFile
+	reader       *bufio.Reader // only for lines mode
+	decoder      *json.Decoder // only for array mode
+}
+
+// ndjson can continue reading from byte offset of a line, while array can't
+type ndjsonParser struct {
+	*jsonParser
+}
+
+type jsonField struct {
+	name   string
+	value  string
+	isNull bool
+}
+
+// failed rows are written as objects in the same layout as input file, all values are written as string
+type jsonFailedRowsWriter struct {
+	file       *os.File
+	writer     *bufio.Writer
+	header     []string
+	errorIndex int
+	lines      bool
+	total      int
+}
+
+func newJSONParser(cfg *config.Input, batchSize int, lines bool) InputParser {
+	parser := &jsonParser{cfg: cfg, batchSize: batchSize, lines: lines}
+	if lines {
+		return &ndjsonParser{parser}
+	}
+
+	return parser
+}
+
+func (parser *jsonParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
+	if parser.file == nil {
+		if err := parser.open(path); err != nil {
+			return nil, false, err
+		}
+	}
+
+	data := [][]string{}
+	offsets := []int64{}
+	for len(data) < parser.batchSize {
+		offset := parser.offset
+		record, err := parser.readRecord()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: %s", parser.currentIndex+1, err)
+		}
+
+		parser.currentIndex += 1
+
+		row, err := parser.constructRow(record)
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: %s", parser.currentIndex, err)
+		}
+
+		data = append(data, row)
+		offsets = append(offsets, offset)
+	}
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+	}
+
+	if parser.lines && !parser.file.transformed {
+		batch.Offsets = append(offsets, parser.offset)
+	}
+
+	// end of file
+	if len(batch.Data) == 0 {
+		parser.Close()
+		return nil, false, nil
+	}
+
+	return batch, true, nil
+}
+
+// union of flattened field names of all records, ordered by first appearance. Field which is always
+// null is dropped if it's the parent of nested fields, e.g. user is null in some records and an object
+// in the others
+func (parser *jsonParser) GetFieldNames(path string) ([]string, error) {
+	scanner := &jsonParser{cfg: parser.cfg, lines: parser.lines}
+	if err := scanner.open(path); err != nil {
+		return nil, err
+	}
+
+	defer scanner.Close()
+
+	allNames := []string{}
+	seen := map[string]bool{}
+	hasValue := map[string]bool{}
+	for {
+		record, err := scanner.readRecord()
+		if err == io.EOF {
+			break
+		}
+
+		scanner.currentIndex += 1
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %s", scanner.currentIndex, err)
+		}
+
+		fields, err := flattenJSONObject(record)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %s", scanner.currentIndex, err)
+		}
+
+		for _, f := range fields {
+			if !seen[f.name] {
+				seen[f.name] = true
+				allNames = append(allNames, f.name)
+			}
+
+			if !f.isNull {
+				hasValue[f.name] = true
+			}
+		}
+	}
+
+	parents := map[string]bool{}
+	for _, name := range allNames {
+		parts := strings.Split(name, JSONPathSeparator)
+		for i := 1; i < len(parts); i++ {
+			parents[strings.Join(parts[:i], JSONPathSeparator)] = true
+		}
+	}
+
+	fieldNames := []string{}
+	parser.fieldIndex = map[string]int{}
+	for _, name := range allNames {
+		if parents[name] && !hasValue[name] {
+			continue
+		}
+
+		parser.fieldIndex[name] = len(fieldNames)
+		fieldNames = append(fieldNames, name)
+	}
+
+	return fieldNames, nil
+}
+
+func (parser *jsonParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *jsonParser) Close() {
+	if parser.file != nil {
+		parser.file.Close()
+		parser.reset()
+	}
+}
+
+// continue reading from byte offset of a line
+func (parser *ndjsonParser) SeekRow(path string, offset int64, index int) error {
+	parser.Close()
+
+	f, err := openInputFile(path, parser.cfg, offset)
+	if err != nil {
+		return err
+	}
+
+	parser.file = f
+	parser.reader = bufio.NewReader(f)
+	parser.offset = offset
+	parser.currentIndex = index
+
+	return nil
+}
+
+func (parser *jsonParser) open(path string) error {
+	f, err := openInputFile(path, parser.cfg, 0)
+	if err != nil {
+		return err
+	}
+
+	if parser.lines {
+		parser.file = f
+		parser.reader = bufio.NewReader(f)
+		parser.offset = f.baseOffset
+		return nil
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	token, err := decoder.Token()
+	if err == io.EOF {
+		f.Close()
+		return fmt.Errorf("input file is empty, expected JSON array")
+	}
+
+	if delim, ok := token.(json.Delim); err != nil || !ok || delim != '[' {
+		f.Close()
+		return fmt.Errorf("input file is not a JSON array")
+	}
+
+	parser.file = f
+	parser.decoder = decoder
+
+	return nil
+}
+
+// raw JSON of the next record, blank lines are skipped in lines mode
+func (parser *jsonParser) readRecord() (json.RawMessage, error) {
+	if !parser.lines {
+		if !parser.decoder.More() {
+			return nil, io.EOF
+		}
+
+		var record json.RawMessage
+		err := parser.decoder.Decode(&record)
+
+		return record, err
+	}
+
+	for {
+		line, err := parser.reader.ReadBytes('\n')
+		parser.offset += int64(len(line))
+
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+}
+
+func (parser *jsonParser) constructRow(record json.RawMessage) ([]string, error) {
+	fields, err := flattenJSONObject(record)
+	if err != nil {
+		return nil, err
+	}
+
+	row := make([]string, len(parser.fieldIndex))
+	for _, f := range fields {
+		i, exists := parser.fieldIndex[f.name]
+		if !exists && f.isNull {
+			continue
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("unknown field '%s', input file is modified while reading", f.name)
+		}
+
+		row[i] = f.value
+	}
+
+	return row, nil
+}
+
+func (parser *jsonParser) reset() {
+	parser.file = nil
+	parser.reader = nil
+	parser.decoder = nil
+	parser.offset = 0
+	parser.currentIndex = 0
+}
+
+// fields of an object in the original order. Nested objects are flattened, arrays are kept as
+// compact JSON, and null is converted to empty string
+func flattenJSONObject(record json.RawMessage) ([]jsonField, error) {
+	res := []jsonField{}
+	if err := flattenJSONValue("", record, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func flattenJSONValue(prefix string, raw json.RawMessage, res *[]jsonField) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("expected JSON object")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		name := prefix + token.(string)
+		switch value[0] {
+		case '{':
+			if err := flattenJSONValue(name+JSONPathSeparator, value, res); err != nil {
+				return err
+			}
+		case '"':
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				return err
+			}
+
+			*res = append(*res, jsonField{name: name, value: s})
+		case 'n':
+			*res = append(*res, jsonField{name: name, isNull: true})
+		case '[':
+			compacted := &bytes.Buffer{}
+			if err := json.Compact(compacted, value); err != nil {
+				return err
+			}
+
+			*res = append(*res, jsonField{name: name, value: compacted.String()})
+		default:
+			// number and boolean are kept as written in input file
+			*res = append(*res, jsonField{name: name, value: string(value)})
+		}
+	}
+
+	return nil
+}
+
+func newJSONFailedRowsWriter(path string, fieldNames []string, lines bool) (*jsonFailedRowsWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, errorIndex := withErrorColumn(fieldNames)
+	writer := &jsonFailedRowsWriter{
+		file:       f,
+		writer:     bufio.NewWriter(f),
+		header:     header,
+		errorIndex: errorIndex,
+		lines:      lines,
+	}
+
+	if !lines {
+		writer.writer.WriteString("[")
+	}
+
+	return writer, nil
+}
+
+func (writer *jsonFailedRowsWriter) Write(row []string, errMsg string) error {
+	obj := newJSONObject()
+	// json rows always have the same total columns as header
+	for i, val := range setErrorColumn(row, len(writer.header), writer.errorIndex, errMsg) {
+		obj.setPath(writer.header[i], val)
+	}
+
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	if !writer.lines && writer.total > 0 {
+		writer.writer.WriteString(",")
+	}
+
+	writer.writer.WriteString("\n")
+	writer.writer.Write(encoded)
+	writer.total += 1
+
+	return nil
+}
+
+func (writer *jsonFailedRowsWriter) Close() error {
+	if !writer.lines {
+		writer.writer.WriteString("\n]")
+	}
+
+	writer.writer.WriteString("\n")
+	if err := writer.writer.Flush(); err != nil {
+		writer.file.Close()
+		return err
+	}
+
+	return writer.file.Close()
+}
+
+// object which keeps the order of its keys when encoded
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{values: map[string]any{}}
+}
+
+// set value of dotted path, the full path is used as key if a parent is already used by a non object value
+func (obj *jsonObject) setPath(path string, value string) {
+	parent, rest, nested := strings.Cut(path, JSONPathSeparator)
+	if nested {
+		existing, exists := obj.values[parent]
+		if !exists {
+			child := newJSONObject()
+			obj.set(parent, child)
+			child.setPath(rest, value)
+			return
+		}
+
+		if child, ok := existing.(*jsonObject); ok {
+			child.setPath(rest, value)
+			return
+		}
+	}
+
+	obj.set(path, value)
+}
+
+func (obj *jsonObject) set(key string, value any) {
+	if _, exists := obj.values[key]; !exists {
+		obj.keys = append(obj.keys, key)
+	}
+
+	obj.values[key] = value
+}
+
+func (obj *jsonObject) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{")
+
+	for i, key := range obj.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		encodedValue, err := json.Marshal(obj.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(encodedKey)
+		buf.WriteString(":")
+		buf.Write(encodedValue)
+	}
+
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
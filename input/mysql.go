@@ -0,0 +1,227 @@
+package input
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// rows of select query are read in pages ordered by key column. Each page continues after the last key of
+// the previous page, so reading a page doesn't get slower like OFFSET does. Key column must be unique and
+// not null, otherwise rows may be skipped
+type mySQLParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int
+	db           *gorm.DB
+	fieldNames   []string
+	keyIndex     int
+	numericKey   bool    // integer key is compared as number, comparing it with string loses precision of big value
+	lastKey      *string // nil before the first page is read
+	done         bool
+}
+
+func newMySQLParser(cfg *config.Input, batchSize int) (*mySQLParser, error) {
+	if strings.TrimSpace(cfg.Query) == "" {
+		return nil, fmt.Errorf("query is required for mysql input")
+	}
+
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("key column is required for mysql input")
+	}
+
+	return &mySQLParser{cfg: cfg, batchSize: batchSize}, nil
+}
+
+// time is not parsed, so date and time columns are read as their text in database
+func (parser *mySQLParser) open() error {
+	if parser.db != nil {
+		return nil
+	}
+
+	connStr := "%s:%s@tcp(%s:%d)/%s?charset=utf8mb4"
+	connStr = fmt.Sprintf(connStr, parser.cfg.Username, parser.cfg.Password, parser.cfg.Host, parser.cfg.Port, parser.cfg.Name)
+	db, err := gorm.Open(mysql.Open(connStr))
+	if err != nil {
+		return err
+	}
+
+	parser.db = db
+	return nil
+}
+
+// column names of query result, the query is executed without returning any row
+func (parser *mySQLParser) GetFieldNames(_ string) ([]string, error) {
+	if parser.fieldNames != nil {
+		return parser.fieldNames, nil
+	}
+
+	if err := parser.open(); err != nil {
+		return nil, err
+	}
+
+	rows, err := parser.db.Raw(fmt.Sprintf("SELECT * FROM (%s) AS `source` LIMIT 0", parser.getQuery())).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	fieldNames := []string{}
+	parser.keyIndex = -1
+	for i, column := range columns {
+		fieldNames = append(fieldNames, column.Name())
+
+		if column.Name() == parser.cfg.Key {
+			parser.keyIndex = i
+			parser.numericKey = strings.Contains(column.DatabaseTypeName(), "INT")
+		}
+	}
+
+	if parser.keyIndex < 0 {
+		return nil, fmt.Errorf("key column '%s' is not found in query result, available columns: %s", parser.cfg.Key, strings.Join(fieldNames, ", "))
+	}
+
+	parser.fieldNames = fieldNames
+	return fieldNames, nil
+}
+
+// null is read as empty string and marked in batch nulls, same as null in parquet input
+func (parser *mySQLParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
+	if parser.done {
+		return nil, false, nil
+	}
+
+	if err := parser.open(); err != nil {
+		return nil, false, err
+	}
+
+	if _, err := parser.GetFieldNames(path); err != nil {
+		return nil, false, err
+	}
+
+	query, args := parser.getPageQuery()
+	rows, err := parser.db.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer rows.Close()
+
+	data := [][]string{}
+	nulls := [][]bool{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(parser.fieldNames))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, false, err
+		}
+
+		line := parser.currentIndex + len(data) + 1
+		if !values[parser.keyIndex].Valid {
+			return nil, false, fmt.Errorf("line %d: key column '%s' is null", line, parser.cfg.Key)
+		}
+
+		row := make([]string, len(values))
+		rowNulls := make([]bool, len(values))
+		for i := range values {
+			row[i] = values[i].String
+			rowNulls[i] = !values[i].Valid
+		}
+
+		data = append(data, row)
+		nulls = append(nulls, rowNulls)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(data) < parser.batchSize {
+		parser.done = true
+	}
+
+	// end of query result
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+
+	lastKey := data[len(data)-1][parser.keyIndex]
+	parser.lastKey = &lastKey
+	parser.currentIndex += len(data)
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+		Nulls: nulls,
+	}
+
+	return batch, true, nil
+}
+
+func (parser *mySQLParser) getPageQuery() (query string, args []any) {
+	key := quoteMySQLIdentifier(parser.cfg.Key)
+	if parser.lastKey == nil {
+		query = "SELECT * FROM (%s) AS `source` ORDER BY %s LIMIT %d"
+		return fmt.Sprintf(query, parser.getQuery(), key, parser.batchSize), nil
+	}
+
+	query = "SELECT * FROM (%s) AS `source` WHERE %s > ? ORDER BY %s LIMIT %d"
+	return fmt.Sprintf(query, parser.getQuery(), key, key, parser.batchSize), []any{parser.getKeyArg(*parser.lastKey)}
+}
+
+// query is used as derived table, so trailing semicolon is removed
+func (parser *mySQLParser) getQuery() string {
+	return strings.TrimRight(strings.TrimSpace(parser.cfg.Query), "; \t\r\n")
+}
+
+func (parser *mySQLParser) getKeyArg(key string) any {
+	if !parser.numericKey {
+		return key
+	}
+
+	if val, err := strconv.ParseInt(key, 10, 64); err == nil {
+		return val
+	}
+
+	if val, err := strconv.ParseUint(key, 10, 64); err == nil {
+		return val
+	}
+
+	return key
+}
+
+func (parser *mySQLParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *mySQLParser) Close() {
+	if parser.db == nil {
+		return
+	}
+
+	if sqlDB, err := parser.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	parser.db = nil
+}
+
+func quoteMySQLIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,505 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+	"github.com/ridwanadhip/universal-uploader/util"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/types"
+)
+
+// columns of parquet file are read in pages, so the file is streamed without loading a whole row group.
+// Field names are paths of leaf columns joined with dot, e.g. address.city. Null value is read as empty
+// string and marked in batch nulls
+type parquetParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int
+	reader       *reader.ParquetReader
+	columns      []parquetColumn
+	totalRows    int
+}
+
+type parquetColumn struct {
+	name      string
+	schema    *parquet.SchemaElement
+	repeated  bool  // value of repeated column is read as json array
+	listLevel int32 // definition level of defined list, lower level means the list is null
+}
+
+// parquet reader opens a handle of the file for every column, so each column has its own read position
+type parquetSource struct {
+	io.ReadSeeker
+	path    string
+	content []byte // nil if file is read from disk
+}
+
+func (parser *parquetParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
+	if parser.reader == nil {
+		if err := parser.open(path); err != nil {
+			return nil, false, err
+		}
+	}
+
+	total := util.MinInt(parser.batchSize, parser.totalRows-parser.currentIndex)
+
+	// end of file
+	if total <= 0 {
+		parser.Close()
+		return nil, false, nil
+	}
+
+	data := make([][]string, total)
+	nulls := make([][]bool, total)
+	for i := range data {
+		data[i] = make([]string, len(parser.columns))
+		nulls[i] = make([]bool, len(parser.columns))
+	}
+
+	for i, column := range parser.columns {
+		values, rls, dls, err := parser.reader.ReadColumnByIndex(int64(i), int64(total))
+		if err != nil {
+			return nil, false, fmt.Errorf("column '%s': %s", column.name, err)
+		}
+
+		err = column.read(values, rls, dls, data, nulls, i)
+		if err != nil {
+			return nil, false, fmt.Errorf("column '%s': %s", column.name, err)
+		}
+	}
+
+	parser.currentIndex += total
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - total,
+		Nulls: nulls,
+	}
+
+	return batch, true, nil
+}
+
+func (parser *parquetParser) GetFieldNames(path string) ([]string, error) {
+	scanner := &parquetParser{cfg: parser.cfg}
+	if err := scanner.open(path); err != nil {
+		return nil, err
+	}
+
+	defer scanner.Close()
+
+	fieldNames := []string{}
+	for _, column := range scanner.columns {
+		fieldNames = append(fieldNames, column.name)
+	}
+
+	return fieldNames, nil
+}
+
+func (parser *parquetParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *parquetParser) Close() {
+	if parser.reader != nil {
+		parser.reader.ReadStop()
+		parser.reader.PFile.Close()
+	}
+
+	parser.reader = nil
+	parser.columns = nil
+	parser.currentIndex = 0
+}
+
+// compressed parquet file is decompressed to memory, because footer of parquet file is read before its rows
+func (parser *parquetParser) open(path string) error {
+	input, err := openDecompressedFile(path, parser.cfg)
+	if err != nil {
+		return err
+	}
+
+	src := &parquetSource{path: path}
+	if input.transformed {
+		src.content, err = io.ReadAll(input)
+	}
+
+	input.Close()
+	if err != nil {
+		return err
+	}
+
+	file, err := src.Open(path)
+	if err != nil {
+		return err
+	}
+
+	pr, err := reader.NewParquetColumnReader(file, 1)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("invalid parquet file: %s", err)
+	}
+
+	columns := []parquetColumn{}
+	handler := pr.SchemaHandler
+	for _, inPath := range handler.ValueColumns {
+		column, err := newParquetColumn(handler, inPath)
+		if err != nil {
+			pr.ReadStop()
+			file.Close()
+			return err
+		}
+
+		columns = append(columns, column)
+	}
+
+	parser.reader = pr
+	parser.columns = columns
+	parser.totalRows = int(pr.GetNumRows())
+
+	return nil
+}
+
+// name of the root schema is not a part of field name. Element of standard list is named after the list,
+// e.g. tags instead of tags.list.element
+func newParquetColumn(handler *schema.SchemaHandler, inPath string) (parquetColumn, error) {
+	exPath := strings.Split(handler.InPathToExPath[inPath], common.PAR_GO_PATH_DELIMITER)
+	path := strings.Split(inPath, common.PAR_GO_PATH_DELIMITER)
+	column := parquetColumn{
+		name:   strings.Join(exPath[1:], "."),
+		schema: handler.SchemaElements[handler.MapIndex[inPath]],
+	}
+
+	for i := 2; i <= len(path); i++ {
+		repetition, err := handler.GetRepetitionType(path[:i])
+		if err != nil {
+			return column, err
+		}
+
+		if repetition != parquet.FieldRepetitionType_REPEATED {
+			continue
+		}
+
+		level, err := handler.MaxDefinitionLevel(path[:i-1])
+		if err != nil {
+			return column, err
+		}
+
+		column.repeated = true
+		column.listLevel = level
+
+		parent := handler.SchemaElements[handler.MapIndex[strings.Join(path[:i-1], common.PAR_GO_PATH_DELIMITER)]]
+		isList := parent.GetConvertedType() == parquet.ConvertedType_LIST || (parent.LogicalType != nil && parent.LogicalType.LIST != nil)
+		if isList && i == len(path)-1 && i > 2 {
+			column.name = strings.Join(exPath[1:i-1], ".")
+		}
+
+		break
+	}
+
+	return column, nil
+}
+
+// values of a column are placed to their rows, value with repetition level 0 starts a new row
+func (column parquetColumn) read(values []any, rls, dls []int32, data [][]string, nulls [][]bool, index int) error {
+	if !column.repeated {
+		for i := range data {
+			if i >= len(values) || values[i] == nil {
+				nulls[i][index] = true
+				continue
+			}
+
+			val, err := column.format(values[i])
+			if err != nil {
+				return err
+			}
+
+			data[i][index] = val
+		}
+
+		return nil
+	}
+
+	row := -1
+	var elements []any
+	flush := func() {
+		if row < 0 {
+			return
+		}
+
+		if elements == nil {
+			nulls[row][index] = true
+		} else {
+			data[row][index] = util.Jsonify(elements)
+		}
+	}
+
+	for i, val := range values {
+		if rls[i] == 0 {
+			flush()
+			row += 1
+			elements = nil
+
+			if dls[i] < column.listLevel {
+				continue
+			}
+
+			elements = []any{}
+			if dls[i] == column.listLevel {
+				continue
+			}
+		}
+
+		if row >= len(data) {
+			break
+		}
+
+		if val == nil {
+			elements = append(elements, nil)
+			continue
+		}
+
+		formatted, err := column.format(val)
+		if err != nil {
+			return err
+		}
+
+		if column.isJSONValue() && json.Valid([]byte(formatted)) {
+			elements = append(elements, json.RawMessage(formatted))
+		} else {
+			elements = append(elements, formatted)
+		}
+	}
+
+	flush()
+
+	return nil
+}
+
+// number and boolean elements of a list are written as json values, other elements as json strings
+func (column parquetColumn) isJSONValue() bool {
+	switch column.getType() {
+	case parquet.Type_BOOLEAN, parquet.Type_INT32, parquet.Type_INT64, parquet.Type_FLOAT, parquet.Type_DOUBLE:
+	default:
+		return false
+	}
+
+	switch column.getConvertedType() {
+	case parquet.ConvertedType_DATE, parquet.ConvertedType_TIME_MILLIS, parquet.ConvertedType_TIME_MICROS,
+		parquet.ConvertedType_TIMESTAMP_MILLIS, parquet.ConvertedType_TIMESTAMP_MICROS:
+		return false
+	}
+
+	logical := column.schema.LogicalType
+	return logical == nil || (logical.DATE == nil && logical.TIME == nil && logical.TIMESTAMP == nil)
+}
+
+// value is converted based on its logical type, or its converted type in file written by older writer.
+// Date and time are written in UTC
+func (column parquetColumn) format(val any) (string, error) {
+	logical := column.schema.LogicalType
+	if logical == nil {
+		logical = &parquet.LogicalType{}
+	}
+
+	converted := column.getConvertedType()
+
+	switch {
+	case logical.DECIMAL != nil:
+		return formatParquetDecimal(val, int(logical.DECIMAL.Scale))
+	case converted == parquet.ConvertedType_DECIMAL:
+		return formatParquetDecimal(val, int(column.schema.GetScale()))
+	case logical.DATE != nil || converted == parquet.ConvertedType_DATE:
+		days, ok := val.(int32)
+		if !ok {
+			return "", fmt.Errorf("invalid date value %v", val)
+		}
+
+		return time.Unix(int64(days)*86400, 0).UTC().Format("2006-01-02"), nil
+	case logical.TIMESTAMP != nil:
+		return formatParquetTimestamp(val, getTimeUnit(logical.TIMESTAMP.Unit))
+	case converted == parquet.ConvertedType_TIMESTAMP_MILLIS:
+		return formatParquetTimestamp(val, time.Millisecond)
+	case converted == parquet.ConvertedType_TIMESTAMP_MICROS:
+		return formatParquetTimestamp(val, time.Microsecond)
+	case logical.TIME != nil:
+		return formatParquetTime(val, getTimeUnit(logical.TIME.Unit))
+	case converted == parquet.ConvertedType_TIME_MILLIS:
+		return formatParquetTime(val, time.Millisecond)
+	case converted == parquet.ConvertedType_TIME_MICROS:
+		return formatParquetTime(val, time.Microsecond)
+	case logical.UUID != nil:
+		b := []byte(fmt.Sprint(val))
+		if len(b) != 16 {
+			return "", fmt.Errorf("invalid uuid length %d", len(b))
+		}
+
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+	case (logical.INTEGER != nil && !logical.INTEGER.IsSigned) || isUnsignedConvertedType(converted):
+		switch v := val.(type) {
+		case int32:
+			return strconv.FormatUint(uint64(uint32(v)), 10), nil
+		case int64:
+			return strconv.FormatUint(uint64(v), 10), nil
+		}
+	}
+
+	switch v := val.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case string:
+		// legacy timestamp
+		if column.getType() == parquet.Type_INT96 {
+			return types.INT96ToTime(v).UTC().Format("2006-01-02 15:04:05.999999999"), nil
+		}
+
+		return v, nil
+	}
+
+	return fmt.Sprint(val), nil
+}
+
+func (column parquetColumn) getType() parquet.Type {
+	if column.schema.Type == nil {
+		return -1
+	}
+
+	return *column.schema.Type
+}
+
+func (column parquetColumn) getConvertedType() parquet.ConvertedType {
+	if column.schema.ConvertedType == nil {
+		return -1
+	}
+
+	return *column.schema.ConvertedType
+}
+
+func isUnsignedConvertedType(converted parquet.ConvertedType) bool {
+	switch converted {
+	case parquet.ConvertedType_UINT_8, parquet.ConvertedType_UINT_16, parquet.ConvertedType_UINT_32, parquet.ConvertedType_UINT_64:
+		return true
+	}
+
+	return false
+}
+
+func getTimeUnit(unit *parquet.TimeUnit) time.Duration {
+	switch {
+	case unit == nil, unit.MILLIS != nil:
+		return time.Millisecond
+	case unit.MICROS != nil:
+		return time.Microsecond
+	}
+
+	return time.Nanosecond
+}
+
+func formatParquetTimestamp(val any, unit time.Duration) (string, error) {
+	v, ok := val.(int64)
+	if !ok {
+		return "", fmt.Errorf("invalid timestamp value %v", val)
+	}
+
+	return time.Unix(0, v*int64(unit)).UTC().Format("2006-01-02 15:04:05.999999999"), nil
+}
+
+func formatParquetTime(val any, unit time.Duration) (string, error) {
+	var v int64
+	switch t := val.(type) {
+	case int32:
+		v = int64(t)
+	case int64:
+		v = t
+	default:
+		return "", fmt.Errorf("invalid time value %v", val)
+	}
+
+	return time.Time{}.Add(time.Duration(v) * unit).Format("15:04:05.999999999"), nil
+}
+
+// unscaled value of decimal is stored as integer or as big endian two's complement bytes
+func formatParquetDecimal(val any, scale int) (string, error) {
+	unscaled := new(big.Int)
+	switch v := val.(type) {
+	case int32:
+		unscaled.SetInt64(int64(v))
+	case int64:
+		unscaled.SetInt64(v)
+	case string:
+		unscaled.SetBytes([]byte(v))
+		if len(v) > 0 && v[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v)*8)))
+		}
+	default:
+		return "", fmt.Errorf("invalid decimal value %v", val)
+	}
+
+	return formatUnscaledDecimal(unscaled, scale), nil
+}
+
+// decimal text of unscaled value, e.g. 1250 with scale 2 become 12.50
+func formatUnscaledDecimal(unscaled *big.Int, scale int) string {
+	digits := new(big.Int).Abs(unscaled).String()
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+
+	return digits
+}
+
+func (src *parquetSource) Open(_ string) (source.ParquetFile, error) {
+	if src.content != nil {
+		return &parquetSource{ReadSeeker: bytes.NewReader(src.content), path: src.path, content: src.content}, nil
+	}
+
+	f, err := os.Open(src.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parquetSource{ReadSeeker: f, path: src.path}, nil
+}
+
+func (src *parquetSource) Create(_ string) (source.ParquetFile, error) {
+	return nil, fmt.Errorf("parquet input is read only")
+}
+
+func (src *parquetSource) Write(_ []byte) (int, error) {
+	return 0, fmt.Errorf("parquet input is read only")
+}
+
+func (src *parquetSource) Close() error {
+	if closer, ok := src.ReadSeeker.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package input
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go/types"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type parquetTestRow struct {
+	ID      int32    `parquet:"name=id, type=INT32"`
+	Amount  int64    `parquet:"name=amount, type=INT64, convertedtype=DECIMAL, scale=2, precision=18"`
+	Price   string   `parquet:"name=price, type=BYTE_ARRAY, convertedtype=DECIMAL, scale=2, precision=20"`
+	Rate    int32    `parquet:"name=rate, type=INT32, scale=3, precision=9, logicaltype=DECIMAL, logicaltype.precision=9, logicaltype.scale=3"`
+	Created int64    `parquet:"name=created, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"`
+	Updated int64    `parquet:"name=updated, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Legacy  string   `parquet:"name=legacy, type=INT96"`
+	Note    *string  `parquet:"name=note, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Tags    []string `parquet:"name=tags, type=LIST, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+	Scores  []int32  `parquet:"name=scores, type=INT32, repetitiontype=REPEATED"`
+	Labels  *[]int32 `parquet:"name=labels, type=LIST, repetitiontype=OPTIONAL, valuetype=INT32"`
+}
+
+func writeParquetTestFile(t *testing.T, rows []parquetTestRow) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	pw, err := writer.NewParquetWriterFromWriter(buf, new(parquetTestRow), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range rows {
+		if err := pw.Write(rows[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pw.WriteStop(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestParquetInput(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	legacy := time.Date(2023, 12, 31, 23, 59, 59, 500000000, time.UTC)
+	note := "first"
+
+	content := writeParquetTestFile(t, []parquetTestRow{
+		{
+			ID:      1,
+			Amount:  -1250,
+			Price:   string([]byte{0x30, 0x39}),
+			Rate:    5,
+			Created: created.UnixMicro(),
+			Updated: created.UnixMilli(),
+			Legacy:  types.TimeToINT96(legacy),
+			Note:    &note,
+			Tags:    []string{"a", "b"},
+			Scores:  []int32{10, 20},
+			Labels:  &[]int32{3},
+		},
+		{
+			ID:      2,
+			Amount:  7,
+			Price:   string([]byte{0xff, 0x38}),
+			Rate:    -1500,
+			Created: 0,
+			Updated: 0,
+			Legacy:  types.TimeToINT96(time.Unix(0, 0)),
+		},
+	})
+
+	cfg := `
+batchSize: 10
+input:
+  type: parquet
+`
+	parser := newTestParser(t, cfg, "data.parquet", map[string]string{"data.parquet": content})
+
+	wantNames := []string{"id", "amount", "price", "rate", "created", "updated", "legacy", "note", "tags", "scores", "labels"}
+	if !reflect.DeepEqual(parser.fieldNames, wantNames) {
+		t.Errorf("got field names %q, want %q", parser.fieldNames, wantNames)
+	}
+
+	rows, lines, batches := readAll(t, parser)
+
+	// decimal keeps its scale, timestamps are written in UTC, and repeated columns are written as json arrays
+	wantRows := [][]string{
+		{"1", "-12.50", "123.45", "0.005", "2024-01-02 03:04:05.123456", "2024-01-02 03:04:05.123", "2023-12-31 23:59:59.5", "first", `["a","b"]`, "[10,20]", "[3]"},
+		{"2", "0.07", "-2.00", "-1.500", "1970-01-01 00:00:00", "1970-01-01 00:00:00", "1970-01-01 00:00:00", "", "[]", "[]", ""},
+	}
+
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("got rows %q, want %q", rows, wantRows)
+	}
+
+	if want := []int{1, 2}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+
+	// optional value and optional list are null, while list without element is empty
+	wantNulls := [][]bool{
+		{false, false, false, false, false, false, false, false, false, false, false},
+		{false, false, false, false, false, false, false, true, false, false, true},
+	}
+
+	if len(batches) != 1 || !reflect.DeepEqual(batches[0].Nulls, wantNulls) {
+		t.Errorf("got nulls %v, want %v", batches[0].Nulls, wantNulls)
+	}
+}
+
+func TestFormatParquetDecimal(t *testing.T) {
+	tests := []struct {
+		val   any
+		scale int
+		want  string
+	}{
+		{int32(1250), 2, "12.50"},
+		{int32(-5), 2, "-0.05"},
+		{int64(123), 0, "123"},
+		{string([]byte{0x01, 0x00}), 1, "25.6"},
+		{string([]byte{0xff, 0xff}), 3, "-0.001"},
+		{string([]byte{0x80, 0x00}), 0, "-32768"},
+	}
+
+	for _, tt := range tests {
+		got, err := formatParquetDecimal(tt.val, tt.scale)
+		if err != nil {
+			t.Errorf("formatParquetDecimal(%v, %d) returns error: %s", tt.val, tt.scale, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("formatParquetDecimal(%v, %d) = %s, want %s", tt.val, tt.scale, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/ridwanadhip/universal-uploader/config"
+	"github.com/ridwanadhip/universal-uploader/util"
+)
+
+// columns of redis input, followed by hash fields of input config
+const (
+	RedisKeyColumn   = "key"
+	RedisTypeColumn  = "type"
+	RedisTTLColumn   = "ttl"
+	RedisValueColumn = "value"
+)
+
+// TTL of key without expiration, same as reply of redis TTL command
+const RedisNoTTL = "-1"
+
+// keys are scanned with SCAN, so the server is not blocked like KEYS does. A key may be read more than once
+// if the key space is changed while scanning, and keys which are removed after scanned are skipped
+type redisParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int
+	db           int
+	client       *redis.Client
+	cursor       uint64
+	pending      []string // scanned keys which are not read yet
+	scanned      bool     // true if the last scan cursor is reached
+}
+
+func newRedisParser(cfg *config.Input, batchSize int) (*redisParser, error) {
+	db := 0
+	if cfg.Name != "" {
+		val, err := strconv.Atoi(cfg.Name)
+		if err != nil || val < 0 {
+			return nil, fmt.Errorf("redis input name must be a database number: %s", cfg.Name)
+		}
+
+		db = val
+	}
+
+	return &redisParser{cfg: cfg, batchSize: batchSize, db: db}, nil
+}
+
+func (parser *redisParser) open() error {
+	if parser.client != nil {
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", parser.cfg.Host, parser.cfg.Port),
+		Username: parser.cfg.Username,
+		Password: parser.cfg.Password,
+		DB:       parser.db,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return err
+	}
+
+	parser.client = client
+	return nil
+}
+
+func (parser *redisParser) GetFieldNames(_ string) ([]string, error) {
+	fieldNames := []string{RedisKeyColumn, RedisTypeColumn, RedisTTLColumn, RedisValueColumn}
+
+	exists := map[string]bool{}
+	for _, name := range fieldNames {
+		exists[name] = true
+	}
+
+	for _, name := range parser.cfg.HashFields {
+		if exists[name] {
+			return nil, fmt.Errorf("redis hash field '%s' is used more than once or conflicts with input column", name)
+		}
+
+		exists[name] = true
+		fieldNames = append(fieldNames, name)
+	}
+
+	return fieldNames, nil
+}
+
+func (parser *redisParser) NextBatch(_ string) (batch *Batch, exists bool, err error) {
+	if err := parser.open(); err != nil {
+		return nil, false, err
+	}
+
+	// every key of a batch may be removed after scanned, so continue to the next keys
+	data := [][]string{}
+	for len(data) == 0 {
+		keys, err := parser.scanKeys()
+		if err != nil {
+			return nil, false, err
+		}
+
+		// end of scan
+		if len(keys) == 0 {
+			return nil, false, nil
+		}
+
+		data, err = parser.readKeys(keys)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	parser.currentIndex += len(data)
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+	}
+
+	return batch, true, nil
+}
+
+// count of SCAN is only a hint, so scanned keys which exceed batch size are kept for the next batch
+func (parser *redisParser) scanKeys() ([]string, error) {
+	pattern := parser.cfg.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	ctx := context.Background()
+	for len(parser.pending) < parser.batchSize && !parser.scanned {
+		keys, cursor, err := parser.client.ScanType(ctx, parser.cursor, pattern, int64(parser.batchSize), parser.cfg.KeyType).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		parser.pending = append(parser.pending, keys...)
+		parser.cursor = cursor
+		parser.scanned = cursor == 0
+	}
+
+	total := util.MinInt(len(parser.pending), parser.batchSize)
+	keys := parser.pending[:total]
+	parser.pending = parser.pending[total:]
+
+	return keys, nil
+}
+
+// type and TTL of keys are read first, then their values are read based on the type. Both are sent as pipeline
+func (parser *redisParser) readKeys(keys []string) ([][]string, error) {
+	ctx := context.Background()
+
+	typeCmds := make([]*redis.StatusCmd, len(keys))
+	ttlCmds := make([]*redis.DurationCmd, len(keys))
+	_, err := parser.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			typeCmds[i] = pipe.Type(ctx, key)
+			ttlCmds[i] = pipe.PTTL(ctx, key)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	valueCmds := make([]redis.Cmder, len(keys))
+	_, err = parser.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			switch typeCmds[i].Val() {
+			case "string":
+				valueCmds[i] = pipe.Get(ctx, key)
+			case "hash":
+				valueCmds[i] = pipe.HGetAll(ctx, key)
+			case "list":
+				valueCmds[i] = pipe.LRange(ctx, key, 0, -1)
+			case "set":
+				valueCmds[i] = pipe.SMembers(ctx, key)
+			case "zset":
+				valueCmds[i] = pipe.ZRangeWithScores(ctx, key, 0, -1)
+			}
+		}
+
+		return nil
+	})
+
+	// pipeline returns error of the first failed command, so key which is removed or changed after scanned
+	// doesn't stop reading the other keys
+	if err != nil {
+		for i := range valueCmds {
+			if cmdErr := getRedisCmdError(valueCmds[i]); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) && !isRedisWrongTypeError(cmdErr) {
+				return nil, cmdErr
+			}
+		}
+	}
+
+	data := [][]string{}
+	for i, key := range keys {
+		keyType := typeCmds[i].Val()
+
+		// key is removed after scanned
+		if keyType == "none" || errors.Is(ttlCmds[i].Err(), redis.Nil) || ttlCmds[i].Val() == -2 {
+			continue
+		}
+
+		// key is removed, or its type is changed after scanned
+		if err := getRedisCmdError(valueCmds[i]); errors.Is(err, redis.Nil) || isRedisWrongTypeError(err) {
+			continue
+		}
+
+		row := []string{key, keyType, formatRedisTTL(ttlCmds[i].Val()), ""}
+		hash := map[string]string{}
+
+		switch cmd := valueCmds[i].(type) {
+		case *redis.StringCmd:
+			row[3] = cmd.Val()
+		case *redis.MapStringStringCmd:
+			hash = cmd.Val()
+			row[3] = util.Jsonify(hash)
+		case *redis.StringSliceCmd:
+			members := cmd.Val()
+			if keyType == "set" {
+				sort.Strings(members)
+			}
+
+			row[3] = util.Jsonify(members)
+		case *redis.ZSliceCmd:
+			members := []map[string]any{}
+			for _, z := range cmd.Val() {
+				members = append(members, map[string]any{"member": z.Member, "score": z.Score})
+			}
+
+			row[3] = util.Jsonify(members)
+		}
+
+		for _, name := range parser.cfg.HashFields {
+			row = append(row, hash[name])
+		}
+
+		data = append(data, row)
+	}
+
+	return data, nil
+}
+
+func getRedisCmdError(cmd redis.Cmder) error {
+	if cmd == nil {
+		return nil
+	}
+
+	return cmd.Err()
+}
+
+func isRedisWrongTypeError(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(redisErr.Error(), "WRONGTYPE")
+}
+
+func (parser *redisParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *redisParser) Close() {
+	if parser.client != nil {
+		parser.client.Close()
+		parser.client = nil
+	}
+}
+
+// TTL is rounded up to seconds, so key which is about to expire doesn't become a key without expiration
+func formatRedisTTL(ttl time.Duration) string {
+	if ttl < 0 {
+		return RedisNoTTL
+	}
+
+	return strconv.FormatInt(int64((ttl+time.Second-1)/time.Second), 10)
+}
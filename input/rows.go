@@ -0,0 +1,93 @@
+package input
+
+import (
+	"io"
+)
+
+// source of rows which is not read from a file, e.g. rows which are already in memory
+type RowSource interface {
+	GetFieldNames() ([]string, error)
+	NextRow() ([]string, error) // returns io.EOF if there is no more row
+}
+
+type sliceRowSource struct {
+	fieldNames []string
+	rows       [][]string
+	index      int
+}
+
+// field names are only read once, then rows are read until the source returns io.EOF
+type rowSourceParser struct {
+	source       RowSource
+	batchSize    int
+	currentIndex int
+	fieldNames   []string
+}
+
+func NewSliceRowSource(fieldNames []string, rows [][]string) RowSource {
+	return &sliceRowSource{fieldNames: fieldNames, rows: rows}
+}
+
+func (source *sliceRowSource) GetFieldNames() ([]string, error) {
+	return source.fieldNames, nil
+}
+
+func (source *sliceRowSource) NextRow() ([]string, error) {
+	if source.index >= len(source.rows) {
+		return nil, io.EOF
+	}
+
+	row := source.rows[source.index]
+	source.index += 1
+
+	return row, nil
+}
+
+func (parser *rowSourceParser) NextBatch(_ string) (batch *Batch, exists bool, err error) {
+	data := [][]string{}
+	for len(data) < parser.batchSize {
+		row, err := parser.source.NextRow()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		parser.currentIndex += 1
+		data = append(data, row)
+	}
+
+	// end of rows
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+	}
+
+	return batch, true, nil
+}
+
+func (parser *rowSourceParser) GetFieldNames(_ string) ([]string, error) {
+	if parser.fieldNames != nil {
+		return parser.fieldNames, nil
+	}
+
+	fieldNames, err := parser.source.GetFieldNames()
+	if err != nil {
+		return nil, err
+	}
+
+	parser.fieldNames = fieldNames
+	return fieldNames, nil
+}
+
+func (parser *rowSourceParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *rowSourceParser) Close() {}
@@ -0,0 +1,298 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+	"github.com/xuri/excelize/v2"
+)
+
+// rows of a worksheet are streamed, worksheet larger than excelize.StreamChunkSize is unzipped to
+// temporary file instead of memory. Empty rows are skipped, but they are counted in row index so line
+// number of a row is its position after header row
+type xlsxParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int // index of the next row, including empty rows
+	totalColumns int // total columns of header, trailing empty cells of data row are trimmed by excelize
+	file         *excelize.File
+	rows         *excelize.Rows
+}
+
+// failed rows are written to a new workbook, in the sheet and header row of input config
+type xlsxFailedRowsWriter struct {
+	path        string
+	file        *excelize.File
+	writer      *excelize.StreamWriter
+	totalFields int
+	errorIndex  int
+	total       int
+}
+
+func (parser *xlsxParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
+	if parser.rows == nil {
+		if err := parser.open(path); err != nil {
+			return nil, false, err
+		}
+
+		// skip header
+		header, err := parser.readHeader()
+		if err != nil {
+			return nil, false, err
+		}
+
+		parser.totalColumns = len(header)
+	}
+
+	data := [][]string{}
+	indexes := []int{}
+	for len(data) < parser.batchSize {
+		columns, err := parser.readRow()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if columns == nil {
+			break
+		}
+
+		for len(columns) < parser.totalColumns {
+			columns = append(columns, "")
+		}
+
+		data = append(data, columns)
+		indexes = append(indexes, parser.currentIndex-1)
+	}
+
+	// end of file
+	if len(data) == 0 {
+		parser.Close()
+		return nil, false, nil
+	}
+
+	batch = &Batch{
+		Data:  data,
+		Index: indexes[0],
+	}
+
+	// index of every row is kept if empty rows are skipped between them
+	if parser.currentIndex-batch.Index != len(data) {
+		batch.Indexes = append(indexes, parser.currentIndex)
+	}
+
+	return batch, true, nil
+}
+
+func (parser *xlsxParser) GetFieldNames(path string) ([]string, error) {
+	scanner := &xlsxParser{cfg: parser.cfg}
+	if err := scanner.open(path); err != nil {
+		return nil, err
+	}
+
+	defer scanner.Close()
+
+	return scanner.readHeader()
+}
+
+func (parser *xlsxParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *xlsxParser) Close() {
+	if parser.rows != nil {
+		parser.rows.Close()
+	}
+
+	if parser.file != nil {
+		parser.file.Close()
+	}
+
+	parser.file = nil
+	parser.rows = nil
+	parser.currentIndex = 0
+}
+
+// compressed workbook is decompressed to memory, because xlsx is a zip archive which can't be read as stream
+func (parser *xlsxParser) open(path string) error {
+	input, err := openDecompressedFile(path, parser.cfg)
+	if err != nil {
+		return err
+	}
+
+	var f *excelize.File
+	opts := excelize.Options{RawCellValue: parser.cfg.RawValues}
+	if input.transformed {
+		f, err = excelize.OpenReader(input, opts)
+	} else {
+		f, err = excelize.OpenFile(path, opts)
+	}
+
+	input.Close()
+	if err != nil {
+		return err
+	}
+
+	sheet, err := getXLSXSheetName(f, parser.cfg.Sheet)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	parser.file = f
+	parser.rows = rows
+
+	return nil
+}
+
+// rows above header row are ignored, e.g. title of the sheet
+func (parser *xlsxParser) readHeader() ([]string, error) {
+	for i := 0; i < parser.cfg.HeaderRow; i++ {
+		if !parser.rows.Next() {
+			if err := parser.rows.Error(); err != nil {
+				return nil, err
+			}
+
+			return nil, fmt.Errorf("header row %d is not found in sheet", parser.cfg.HeaderRow)
+		}
+	}
+
+	header, err := parser.rows.Columns(excelize.Options{RawCellValue: parser.cfg.RawValues})
+	if err != nil {
+		return nil, err
+	}
+
+	if isEmptyRow(header) {
+		return nil, fmt.Errorf("header row %d is empty", parser.cfg.HeaderRow)
+	}
+
+	return header, nil
+}
+
+// next non empty row, returns nil if end of sheet is reached. Every row which is read is counted in
+// current index, including empty rows
+func (parser *xlsxParser) readRow() ([]string, error) {
+	for parser.rows.Next() {
+		parser.currentIndex += 1
+		columns, err := parser.rows.Columns(excelize.Options{RawCellValue: parser.cfg.RawValues})
+		if err != nil {
+			return nil, err
+		}
+
+		if !isEmptyRow(columns) {
+			return columns, nil
+		}
+	}
+
+	return nil, parser.rows.Error()
+}
+
+// find sheet by name, or by 1 based index if there is no sheet with that name
+func getXLSXSheetName(f *excelize.File, sheet string) (string, error) {
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("workbook doesn't have any sheet")
+	}
+
+	if sheet == "" {
+		return sheets[0], nil
+	}
+
+	for _, name := range sheets {
+		if name == sheet {
+			return name, nil
+		}
+	}
+
+	index, err := strconv.Atoi(sheet)
+	if err == nil && index >= 1 && index <= len(sheets) {
+		return sheets[index-1], nil
+	}
+
+	return "", fmt.Errorf("sheet '%s' is not found, available sheets: %s", sheet, strings.Join(sheets, ", "))
+}
+
+func isEmptyRow(columns []string) bool {
+	for _, col := range columns {
+		if col != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sheet is named after the sheet of input config, so it's found by name when the file is uploaded again.
+// Rows above header row are written as placeholder rows
+func newXLSXFailedRowsWriter(path string, fieldNames []string, cfg *config.Input) (*xlsxFailedRowsWriter, error) {
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	if cfg.Sheet != "" {
+		if err := f.SetSheetName(sheet, cfg.Sheet); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		sheet = cfg.Sheet
+	}
+
+	writer, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	header, errorIndex := withErrorColumn(fieldNames)
+	res := &xlsxFailedRowsWriter{path: path, file: f, writer: writer, totalFields: len(fieldNames), errorIndex: errorIndex}
+	for i := 1; i < cfg.HeaderRow; i++ {
+		if err := res.writeRow([]string{FailedRowsPlaceholder}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	if err := res.writeRow(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (writer *xlsxFailedRowsWriter) Write(row []string, errMsg string) error {
+	return writer.writeRow(setErrorColumn(row, writer.totalFields, writer.errorIndex, errMsg))
+}
+
+// cells are written as text, so leading zeros and displayed dates are kept
+func (writer *xlsxFailedRowsWriter) writeRow(row []string) error {
+	writer.total += 1
+
+	cells := make([]interface{}, len(row))
+	for i := range row {
+		cells[i] = row[i]
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, writer.total)
+	if err != nil {
+		return err
+	}
+
+	return writer.writer.SetRow(cell, cells)
+}
+
+func (writer *xlsxFailedRowsWriter) Close() error {
+	defer writer.file.Close()
+
+	if err := writer.writer.Flush(); err != nil {
+		return err
+	}
+
+	return writer.file.SaveAs(writer.path)
+}
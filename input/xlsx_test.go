@@ -0,0 +1,114 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const xlsxTestConfig = `
+batchSize: 2
+input:
+  type: xlsx
+  sheet: Orders
+  headerRow: 2
+`
+
+func writeXLSXTestFile(t *testing.T, sheet string, rows [][]any) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	f.NewSheet(sheet)
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestXLSXInputSkipsEmptyRows(t *testing.T) {
+	content := writeXLSXTestFile(t, "Orders", [][]any{
+		{"Order export"},
+		{"id", "name"},
+		{"1", "a"},
+		{},
+		{"2", "b"},
+		{"3", "c"},
+	})
+
+	parser := newTestParser(t, xlsxTestConfig, "data.xlsx", map[string]string{"data.xlsx": content})
+	rows, lines, _ := readAll(t, parser)
+
+	wantRows := [][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("got rows %q, want %q", rows, wantRows)
+	}
+
+	// empty row is counted, so line is the position of row after header row
+	if want := []int{1, 3, 4}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+}
+
+func TestXLSXFailedRowsRoundTrip(t *testing.T) {
+	content := writeXLSXTestFile(t, "Orders", [][]any{
+		{"Order export"},
+		{"id", "name"},
+		{"1", "a"},
+		{"2", "b"},
+	})
+
+	parser := newTestParser(t, xlsxTestConfig, "data.xlsx", map[string]string{"data.xlsx": content})
+	rows, _, batches := readAll(t, parser)
+
+	path := filepath.Join(t.TempDir(), "data.failed.xlsx")
+	writer, err := parser.NewFailedRowsWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, batch := range batches {
+		for i := range batch.Raw {
+			if err := writer.Write(batch.Raw[i], "invalid name"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	failed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// failed rows file is uploaded again with the same sheet and header row
+	reuploaded := newTestParser(t, xlsxTestConfig, "data.failed.xlsx", map[string]string{"data.failed.xlsx": string(failed)})
+	if want := []string{"id", "name", "upload_error"}; !reflect.DeepEqual(reuploaded.fieldNames, want) {
+		t.Errorf("got field names %q, want %q", reuploaded.fieldNames, want)
+	}
+
+	got, _, _ := readAll(t, reuploaded)
+	if !reflect.DeepEqual(got, rows) {
+		t.Errorf("got rows %q, want %q", got, rows)
+	}
+}
@@ -0,0 +1,313 @@
+package input
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+	"github.com/ridwanadhip/universal-uploader/util"
+)
+
+// child elements of a record are referenced by their path relative to the record, and attributes by @
+// followed by their name, e.g. Customer/Name and Customer/@id
+const (
+	XMLPathSeparator   = "/"
+	XMLAttributePrefix = "@"
+)
+
+// every child of the root element is a record if record path is not set
+const DefaultXMLRecordPath = "/*/*"
+
+// elements which match record path are streamed one by one, so the whole file is never loaded to memory.
+// Namespace prefixes are ignored, and * in record path matches any element
+type xmlParser struct {
+	cfg          *config.Input
+	batchSize    int
+	currentIndex int
+	recordPath   []string
+	fieldIndex   map[string]int // position of field name in row, populated by GetFieldNames
+	file         *inputFile
+	decoder      *xml.Decoder
+	stack        []string // names of open elements outside of record
+}
+
+type xmlField struct {
+	name   string
+	value  string
+	parent bool // element which has child elements, its text is ignored
+}
+
+func newXMLParser(cfg *config.Input, batchSize int) (*xmlParser, error) {
+	recordPath := cfg.RecordPath
+	if recordPath == "" {
+		recordPath = DefaultXMLRecordPath
+	}
+
+	steps := strings.Split(strings.Trim(recordPath, XMLPathSeparator), XMLPathSeparator)
+	for _, step := range steps {
+		if step == "" || strings.HasPrefix(step, XMLAttributePrefix) {
+			return nil, fmt.Errorf("invalid xml record path: %s", recordPath)
+		}
+	}
+
+	return &xmlParser{cfg: cfg, batchSize: batchSize, recordPath: steps}, nil
+}
+
+func (parser *xmlParser) NextBatch(path string) (batch *Batch, exists bool, err error) {
+	if parser.decoder == nil {
+		if err := parser.open(path); err != nil {
+			return nil, false, err
+		}
+	}
+
+	data := [][]string{}
+	for len(data) < parser.batchSize {
+		fields, err := parser.readRecord()
+		if err == io.EOF {
+			break
+		}
+
+		parser.currentIndex += 1
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: %s", parser.currentIndex, err)
+		}
+
+		row, err := parser.constructRow(fields)
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: %s", parser.currentIndex, err)
+		}
+
+		data = append(data, row)
+	}
+
+	// end of file
+	if len(data) == 0 {
+		parser.Close()
+		return nil, false, nil
+	}
+
+	batch = &Batch{
+		Data:  data,
+		Index: parser.currentIndex - len(data),
+	}
+
+	return batch, true, nil
+}
+
+// union of field names of all records, ordered by first appearance. Element which always has child
+// elements is not a field, only its children are
+func (parser *xmlParser) GetFieldNames(path string) ([]string, error) {
+	scanner := &xmlParser{cfg: parser.cfg, recordPath: parser.recordPath}
+	if err := scanner.open(path); err != nil {
+		return nil, err
+	}
+
+	defer scanner.Close()
+
+	fieldNames := []string{}
+	parser.fieldIndex = map[string]int{}
+	for {
+		fields, err := scanner.readRecord()
+		if err == io.EOF {
+			break
+		}
+
+		scanner.currentIndex += 1
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %s", scanner.currentIndex, err)
+		}
+
+		for _, f := range fields {
+			if _, exists := parser.fieldIndex[f.name]; !exists {
+				parser.fieldIndex[f.name] = len(fieldNames)
+				fieldNames = append(fieldNames, f.name)
+			}
+		}
+	}
+
+	if len(fieldNames) == 0 && scanner.currentIndex == 0 {
+		return nil, fmt.Errorf("no element matches xml record path /%s", strings.Join(parser.recordPath, XMLPathSeparator))
+	}
+
+	return fieldNames, nil
+}
+
+func (parser *xmlParser) GetCurrentIndex() int {
+	return parser.currentIndex
+}
+
+func (parser *xmlParser) Close() {
+	if parser.file != nil {
+		parser.file.Close()
+	}
+
+	parser.file = nil
+	parser.decoder = nil
+	parser.stack = nil
+	parser.currentIndex = 0
+}
+
+// input file is already decoded to UTF-8, so encoding in xml declaration is ignored
+func (parser *xmlParser) open(path string) error {
+	f, err := openInputFile(path, parser.cfg, 0)
+	if err != nil {
+		return err
+	}
+
+	decoder := xml.NewDecoder(bufio.NewReader(f))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	parser.file = f
+	parser.decoder = decoder
+
+	return nil
+}
+
+// fields of the next element which matches record path
+func (parser *xmlParser) readRecord() ([]xmlField, error) {
+	for {
+		token, err := parser.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			parser.stack = append(parser.stack, t.Name.Local)
+			if !parser.isRecord() {
+				continue
+			}
+
+			fields, err := readXMLElement(parser.decoder, t)
+			parser.stack = parser.stack[:len(parser.stack)-1]
+
+			return mergeXMLFields(fields), err
+		case xml.EndElement:
+			parser.stack = parser.stack[:len(parser.stack)-1]
+		}
+	}
+}
+
+func (parser *xmlParser) isRecord() bool {
+	if len(parser.stack) != len(parser.recordPath) {
+		return false
+	}
+
+	for i, step := range parser.recordPath {
+		if step != "*" && step != parser.stack[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (parser *xmlParser) constructRow(fields []xmlField) ([]string, error) {
+	row := make([]string, len(parser.fieldIndex))
+	for _, f := range fields {
+		i, exists := parser.fieldIndex[f.name]
+		if !exists {
+			return nil, fmt.Errorf("unknown field '%s', input file is modified while reading", f.name)
+		}
+
+		row[i] = f.value
+	}
+
+	return row, nil
+}
+
+// fields of element and its descendants in document order, until the end of the element is read
+func readXMLElement(decoder *xml.Decoder, start xml.StartElement) ([]xmlField, error) {
+	type node struct {
+		path  string
+		index int // index of the element field, -1 for the record itself
+		text  strings.Builder
+	}
+
+	fields := appendXMLAttributes(nil, "", start.Attr)
+	stack := []*node{{index: -1}}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("element %s is not closed", start.Name.Local)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		current := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			if current.index >= 0 {
+				fields[current.index].parent = true
+			}
+
+			child := &node{path: joinXMLPath(current.path, t.Name.Local), index: len(fields)}
+			fields = append(fields, xmlField{name: child.path})
+			fields = appendXMLAttributes(fields, child.path, t.Attr)
+			stack = append(stack, child)
+		case xml.CharData:
+			current.text.Write(t)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return fields, nil
+			}
+
+			fields[current.index].value = current.text.String()
+		}
+	}
+}
+
+// namespace declarations are not fields
+func appendXMLAttributes(fields []xmlField, path string, attrs []xml.Attr) []xmlField {
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+
+		fields = append(fields, xmlField{name: joinXMLPath(path, XMLAttributePrefix+attr.Name.Local), value: attr.Value})
+	}
+
+	return fields
+}
+
+// repeated element in a record is read as json array of its values, in the position of the first one.
+// Elements with child elements are removed, their children are the fields
+func mergeXMLFields(fields []xmlField) []xmlField {
+	res := []xmlField{}
+	values := map[string][]string{}
+	for _, f := range fields {
+		if f.parent {
+			continue
+		}
+
+		if _, exists := values[f.name]; !exists {
+			res = append(res, f)
+		}
+
+		values[f.name] = append(values[f.name], f.value)
+	}
+
+	for i := range res {
+		if vals := values[res[i].name]; len(vals) > 1 {
+			res[i].value = util.Jsonify(vals)
+		}
+	}
+
+	return res
+}
+
+func joinXMLPath(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+
+	return parent + XMLPathSeparator + name
+}
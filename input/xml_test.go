@@ -0,0 +1,95 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+)
+
+const xmlTestData = `<?xml version="1.0" encoding="UTF-8"?>
+<Export xmlns:o="urn:orders">
+  <Header><Created>2024-01-01</Created></Header>
+  <Orders>
+    <o:Order id="1">
+      <Customer id="c1"><Name>Ann</Name></Customer>
+      <Item>a</Item>
+      <Item>b</Item>
+    </o:Order>
+    <Order id="2">
+      <Customer id="c2"><Name/></Customer>
+      <Note>rush</Note>
+    </Order>
+  </Orders>
+</Export>
+`
+
+func TestXMLInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		recordPath string
+		wantNames  []string
+		wantRows   [][]string
+	}{
+		{
+			name:       "record path with namespace prefix",
+			recordPath: "/Export/Orders/Order",
+			wantNames:  []string{"@id", "Customer/@id", "Customer/Name", "Item", "Note"},
+			wantRows: [][]string{
+				{"1", "c1", "Ann", `["a","b"]`, ""},
+				{"2", "c2", "", "", "rush"},
+			},
+		},
+		{
+			name:       "wildcard record path",
+			recordPath: "/*/Orders/*",
+			wantNames:  []string{"@id", "Customer/@id", "Customer/Name", "Item", "Note"},
+			wantRows: [][]string{
+				{"1", "c1", "Ann", `["a","b"]`, ""},
+				{"2", "c2", "", "", "rush"},
+			},
+		},
+		{
+			name:      "default record path",
+			wantNames: []string{"Created", "Order/@id", "Order/Customer/@id", "Order/Customer/Name", "Order/Item", "Order/Note"},
+			wantRows: [][]string{
+				{"2024-01-01", "", "", "", "", ""},
+				{"", `["1","2"]`, `["c1","c2"]`, `["Ann",""]`, `["a","b"]`, "rush"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := `
+batchSize: 2
+input:
+  type: xml
+`
+			if tt.recordPath != "" {
+				cfg += "  recordPath: " + tt.recordPath + "\n"
+			}
+
+			parser := newTestParser(t, cfg, "data.xml", map[string]string{"data.xml": xmlTestData})
+			if !reflect.DeepEqual(parser.fieldNames, tt.wantNames) {
+				t.Errorf("got field names %q, want %q", parser.fieldNames, tt.wantNames)
+			}
+
+			rows, _, _ := readAll(t, parser)
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("got rows %q, want %q", rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestXMLInvalidRecordPath(t *testing.T) {
+	for _, path := range []string{"/Orders//Order", "/Orders/@id"} {
+		cfg := `
+input:
+  type: xml
+  recordPath: ` + path + `
+`
+		if _, err := openTestParser(t, cfg, "data.xml", map[string]string{"data.xml": xmlTestData}); err == nil {
+			t.Errorf("record path %s is accepted", path)
+		}
+	}
+}
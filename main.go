@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
 	"github.com/ridwanadhip/universal-uploader/config"
 	"github.com/ridwanadhip/universal-uploader/uploader"
 )
@@ -20,6 +24,13 @@ func main() {
 	defer up.Close()
 
 	err = up.Run()
+	if errors.Is(err, uploader.ErrInterrupted) {
+		// deferred functions are not executed by os.Exit
+		up.Close()
+		fmt.Printf("[Signal] %s\n", err)
+		os.Exit(uploader.ExitCodeInterrupted)
+	}
+
 	if err != nil {
 		panic(err)
 	}
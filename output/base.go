@@ -0,0 +1,151 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+type (
+	OutputType string
+	RowStatus  string
+)
+
+// known types
+const (
+	OutputTypeCSV   OutputType = "csv"
+	OutputTypeJSONL OutputType = "jsonl"
+	OutputTypeZip   OutputType = "zip"
+)
+
+// known row statuses
+const (
+	RowStatusOK        RowStatus = "ok"
+	RowStatusFailed    RowStatus = "failed"
+	RowStatusSkipped   RowStatus = "skipped-by-checkpoint"
+	RowStatusValidated RowStatus = "validated" // passed dry run, nothing was committed
+)
+
+// column names of result file
+const (
+	TargetIDColumn = "target_id"
+	LineColumn     = "line"
+	StatusColumn   = "status"
+	ErrorColumn    = "error"
+)
+
+type Writer struct {
+	cfg          *config.Config
+	outputWriter OutputWriter
+}
+
+// result of a single input row for a single target
+type Record struct {
+	TargetID string            `json:"targetId"`
+	Line     int               `json:"line"`
+	Status   RowStatus         `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Values   map[string]string `json:"values,omitempty"`
+}
+
+type OutputWriter interface {
+	Write(record *Record) error
+	Close() error
+}
+
+func NewWriter(cfg *config.Config) (writer Writer, err error) {
+	writer = Writer{cfg: cfg}
+
+	// writer without implementation will ignore every record
+	if !cfg.Output.Enable {
+		return writer, nil
+	}
+
+	switch OutputType(cfg.Output.Type) {
+	case OutputTypeCSV:
+		writer.outputWriter, err = newCSVWriter(cfg.Output.Path, getFieldNames(cfg.Targets...), true)
+	case OutputTypeJSONL:
+		writer.outputWriter, err = newJSONLWriter(cfg.Output.Path)
+	case OutputTypeZip:
+		writer.outputWriter, err = newZipWriter(cfg.Output.Path, cfg)
+	default:
+		err = fmt.Errorf("unknown output writer type: %s", cfg.Output.Type)
+	}
+
+	return writer, err
+}
+
+// write a record for each row of a batch, rows are 0 based index of every row in input. Values of every
+// row are optional
+func (writer *Writer) WriteBatch(targetID string, rows []int, status RowStatus, values []map[string]string, batchErr error) error {
+	if writer.outputWriter == nil {
+		return nil
+	}
+
+	for i, row := range rows {
+		var rowValues map[string]string
+		if i < len(values) {
+			rowValues = values[i]
+		}
+
+		if err := writer.WriteRow(targetID, row+1, status, rowValues, batchErr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write a record for a single row, line is 1 based index of input data
+func (writer *Writer) WriteRow(targetID string, line int, status RowStatus, values map[string]string, rowErr error) error {
+	if writer.outputWriter == nil {
+		return nil
+	}
+
+	record := &Record{
+		TargetID: targetID,
+		Line:     line,
+		Status:   status,
+		Values:   values,
+	}
+
+	if rowErr != nil {
+		record.Error = rowErr.Error()
+	}
+
+	return writer.outputWriter.Write(record)
+}
+
+func (writer *Writer) IsEnabled() bool {
+	return writer.outputWriter != nil
+}
+
+// flush and close result file, safe to be called more than once
+func (writer *Writer) Close() error {
+	if writer.outputWriter == nil {
+		return nil
+	}
+
+	err := writer.outputWriter.Close()
+	writer.outputWriter = nil
+
+	return err
+}
+
+// list of unique target field names, ordered by their first appearance
+func getFieldNames(targets ...config.Target) []string {
+	res := []string{}
+	exists := map[string]bool{}
+
+	for i := range targets {
+		for j := range targets[i].Fields {
+			name := targets[i].Fields[j].Name
+			if !exists[name] {
+				exists[name] = true
+				res = append(res, name)
+			}
+		}
+	}
+
+	return res
+}
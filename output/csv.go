@@ -0,0 +1,71 @@
+package output
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+)
+
+type csvWriter struct {
+	file       *os.File
+	writer     *csv.Writer
+	fieldNames []string
+	withTarget bool // if true then add target id column, used when records of many targets share one file
+}
+
+func newCSVWriter(path string, fieldNames []string, withTarget bool) (*csvWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCSVWriterFromFile(f, fieldNames, withTarget)
+}
+
+func newCSVWriterFromFile(f *os.File, fieldNames []string, withTarget bool) (*csvWriter, error) {
+	writer := &csvWriter{
+		file:       f,
+		writer:     csv.NewWriter(f),
+		fieldNames: fieldNames,
+		withTarget: withTarget,
+	}
+
+	header := []string{}
+	if withTarget {
+		header = append(header, TargetIDColumn)
+	}
+
+	header = append(header, LineColumn, StatusColumn, ErrorColumn)
+	header = append(header, fieldNames...)
+
+	if err := writer.writer.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (writer *csvWriter) Write(record *Record) error {
+	row := []string{}
+	if writer.withTarget {
+		row = append(row, record.TargetID)
+	}
+
+	row = append(row, strconv.Itoa(record.Line), string(record.Status), record.Error)
+	for _, name := range writer.fieldNames {
+		row = append(row, record.Values[name])
+	}
+
+	return writer.writer.Write(row)
+}
+
+func (writer *csvWriter) Close() error {
+	writer.writer.Flush()
+	if err := writer.writer.Error(); err != nil {
+		writer.file.Close()
+		return err
+	}
+
+	return writer.file.Close()
+}
@@ -0,0 +1,37 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+)
+
+type jsonlWriter struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(path string) (*jsonlWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bufio.NewWriter(f)
+	return &jsonlWriter{f, buffer, json.NewEncoder(buffer)}, nil
+}
+
+// encoder append new line after each record
+func (writer *jsonlWriter) Write(record *Record) error {
+	return writer.encoder.Encode(record)
+}
+
+func (writer *jsonlWriter) Close() error {
+	if err := writer.buffer.Flush(); err != nil {
+		writer.file.Close()
+		return err
+	}
+
+	return writer.file.Close()
+}
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ridwanadhip/universal-uploader/config"
+)
+
+const ConfigFileName = "config.json"
+
+// zip entries must be written one at a time, so records of each target are
+// written into temporary csv files first and bundled when the writer is closed
+type zipWriter struct {
+	path      string
+	cfg       *config.Config
+	targetIDs []string
+	writers   map[string]*csvWriter
+}
+
+func newZipWriter(path string, cfg *config.Config) (*zipWriter, error) {
+	writer := &zipWriter{
+		path:      path,
+		cfg:       cfg,
+		targetIDs: []string{},
+		writers:   map[string]*csvWriter{},
+	}
+
+	for i := range cfg.Targets {
+		t := cfg.Targets[i]
+
+		f, err := os.CreateTemp("", "universal-uploader-result-*.csv")
+		if err != nil {
+			writer.cleanUp()
+			return nil, err
+		}
+
+		csvWriter, err := newCSVWriterFromFile(f, getFieldNames(t), false)
+		if err != nil {
+			os.Remove(f.Name())
+			writer.cleanUp()
+			return nil, err
+		}
+
+		writer.targetIDs = append(writer.targetIDs, t.ID)
+		writer.writers[t.ID] = csvWriter
+	}
+
+	return writer, nil
+}
+
+func (writer *zipWriter) Write(record *Record) error {
+	csvWriter, exists := writer.writers[record.TargetID]
+	if !exists {
+		return fmt.Errorf("unknown target id: %s", record.TargetID)
+	}
+
+	return csvWriter.Write(record)
+}
+
+func (writer *zipWriter) Close() error {
+	defer writer.cleanUp()
+
+	for _, id := range writer.targetIDs {
+		if err := writer.writers[id].Close(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(writer.path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+	for _, id := range writer.targetIDs {
+		if err := copyToArchive(archive, id+".csv", writer.writers[id].file.Name()); err != nil {
+			return err
+		}
+	}
+
+	entry, err := archive.Create(ConfigFileName)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(writer.cfg.Redacted()); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func (writer *zipWriter) cleanUp() {
+	for _, csvWriter := range writer.writers {
+		csvWriter.file.Close()
+		os.Remove(csvWriter.file.Name())
+	}
+}
+
+func copyToArchive(archive *zip.Writer, name, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, src)
+	return err
+}
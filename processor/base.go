@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ridwanadhip/universal-uploader/config"
@@ -25,19 +26,33 @@ const (
 )
 
 type Processor struct {
-	ID       string
-	cfg      *config.Config
-	target   *config.Target
-	impl     Implementation
-	procHook hook.ProcessorHook
+	ID          string
+	cfg         *config.Config
+	target      *config.Target
+	impl        Implementation
+	procHook    hook.ProcessorHook
+	interrupted <-chan struct{}
 }
 
+// result may be partially filled when an error occurred, for example when only some rows are resolved.
+// Nulls marks input values which are null, it's nil if input doesn't have null value
 type Implementation interface {
-	Process(data [][]string) error
-	DryRun(data [][]string) error
+	Process(data [][]string, nulls [][]bool) (*Result, error)
+	DryRun(data [][]string, nulls [][]bool, rows []int) (*Result, error)
+	ClassifyError(err error) ErrorClass
+	Reconnect() error
 	Close()
 }
 
+// summary of a batch that was executed to the target
+type Result struct {
+	TotalRows    int
+	AffectedRows int64
+	Warnings     []string            // only filled during dry run
+	Values       []map[string]string // resolved value of each row, map of target field name to formatted value
+	RowErrors    []error             // error of each row, only filled when batch is executed row by row
+}
+
 func NewProcessor(cfg *config.Config, id string, procHook hook.ProcessorHook) (processor Processor, err error) {
 	target, exists := cfg.TargetMap[id]
 	if !exists {
@@ -59,24 +74,107 @@ func NewProcessor(cfg *config.Config, id string, procHook hook.ProcessorHook) (p
 		return Processor{}, err
 	}
 
-	return Processor{id, cfg, target, impl, procHook}, nil
+	return Processor{id, cfg, target, impl, procHook, nil}, nil
+}
+
+// when the channel is closed, waiting for next retry attempt is aborted
+func (proc *Processor) SetInterrupt(interrupted <-chan struct{}) {
+	proc.interrupted = interrupted
+}
+
+// if target isolate rows then a failed batch is executed again row by row, the error of each row is
+// returned in result instead and the batch itself is not considered as failed
+func (proc *Processor) Process(data [][]string, nulls [][]bool, rows []int) (res *Result, err error) {
+	err = proc.runBatch(data, rows, func() error {
+		res, err = proc.withRetry(rows, func() (*Result, error) {
+			return proc.impl.Process(data, nulls)
+		})
+
+		if err != nil && proc.target.IsolateRows && len(data) > 1 && !errors.Is(err, ErrRetryAborted) {
+			fmt.Printf("[Target ID: %s] line %d to %d failed, retrying row by row: %s\n", proc.ID, rows[0]+1, rows[len(rows)-1]+1, err)
+			res, err = proc.processRowByRow(data, nulls, rows, func(row [][]string, rowNulls [][]bool, _ []int) (*Result, error) {
+				return proc.impl.Process(row, rowNulls)
+			})
+		}
+
+		return err
+	})
+
+	return res, err
+}
+
+// validate a batch against the target without committing anything. Prepare and clean up batch hooks are
+// not called, because they may have side effects outside of the target
+func (proc *Processor) DryRun(data [][]string, nulls [][]bool, rows []int) (res *Result, err error) {
+	res, err = proc.withRetry(rows, func() (*Result, error) {
+		return proc.impl.DryRun(data, nulls, rows)
+	})
+
+	if err != nil && proc.target.IsolateRows && len(data) > 1 && !errors.Is(err, ErrRetryAborted) {
+		fmt.Printf("[Target ID: %s] [Dry Run] line %d to %d failed, retrying row by row: %s\n", proc.ID, rows[0]+1, rows[len(rows)-1]+1, err)
+		res, err = proc.processRowByRow(data, nulls, rows, proc.impl.DryRun)
+	}
+
+	return res, err
+}
+
+// execution is stopped when waiting for retry is aborted, the result only contains rows which are executed
+// before the aborted row
+func (proc *Processor) processRowByRow(data [][]string, nulls [][]bool, rows []int, execute func(data [][]string, nulls [][]bool, rows []int) (*Result, error)) (*Result, error) {
+	res := &Result{
+		TotalRows: len(data),
+		Warnings:  []string{},
+		Values:    []map[string]string{},
+		RowErrors: []error{},
+	}
+
+	for i := range data {
+		var rowNulls [][]bool
+		if nulls != nil {
+			rowNulls = nulls[i : i+1]
+		}
+
+		rowRes, err := proc.withRetry(rows[i:i+1], func() (*Result, error) {
+			return execute(data[i:i+1], rowNulls, rows[i:i+1])
+		})
+
+		if errors.Is(err, ErrRetryAborted) {
+			res.TotalRows = i
+			return res, err
+		}
+
+		values := map[string]string{}
+		if rowRes != nil {
+			res.AffectedRows += rowRes.AffectedRows
+			res.Warnings = append(res.Warnings, rowRes.Warnings...)
+
+			if len(rowRes.Values) > 0 {
+				values = rowRes.Values[0]
+			}
+		}
+
+		res.Values = append(res.Values, values)
+		res.RowErrors = append(res.RowErrors, err)
+	}
+
+	return res, nil
 }
 
-func (proc *Processor) Process(data [][]string, index int) error {
+func (proc *Processor) runBatch(data [][]string, rows []int, execute func() error) error {
 	md := hook.NewProcessorHookMetadataFromTarget(proc.target)
 
 	// perform batch prepartion here via hook
 	if proc.procHook != nil {
-		if err := proc.procHook.PrepareBatch(md, index, len(data)); err != nil {
+		if err := proc.procHook.PrepareBatch(md, rows[0], len(data)); err != nil {
 			return err
 		}
 	}
 
-	err := proc.impl.Process(data)
+	err := execute()
 
 	// perform batch clean up here via hook
 	if proc.procHook != nil {
-		if hookErr := proc.procHook.CleanUpBatch(md, index, len(data), err); hookErr != nil {
+		if hookErr := proc.procHook.CleanUpBatch(md, rows[0], len(data), err); hookErr != nil {
 			return hookErr
 		}
 	}
@@ -84,10 +182,6 @@ func (proc *Processor) Process(data [][]string, index int) error {
 	return err
 }
 
-func (proc *Processor) DryRun(data [][]string) error {
-	return proc.impl.DryRun(data)
-}
-
 func (proc *Processor) Close() {
 	if proc.impl != nil {
 		proc.impl.Close()
@@ -1,6 +1,8 @@
 package processor
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,6 +12,7 @@ import (
 	"github.com/ridwanadhip/universal-uploader/hook"
 	"github.com/ridwanadhip/universal-uploader/util"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -17,6 +20,16 @@ import (
 
 const NullExpr = "NULL"
 
+// mysql error numbers used for error classification
+const (
+	mySQLErrTooManyConnections = 1040
+	mySQLErrServerShutdown     = 1053
+	mySQLErrLockWaitTimeout    = 1205
+	mySQLErrDeadlock           = 1213
+	mySQLErrServerGone         = 2006
+	mySQLErrServerLost         = 2013
+)
+
 type mySQLImplementation struct {
 	input       *config.Input
 	target      *config.Target
@@ -26,9 +39,7 @@ type mySQLImplementation struct {
 }
 
 func NewMySQLImplementation(input *config.Input, target *config.Target, verboseMode bool, procHook hook.ProcessorHook) (*mySQLImplementation, error) {
-	connStr := "%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local"
-	connStr = fmt.Sprintf(connStr, target.Username, target.Password, target.Host, target.Port, target.Name)
-	db, err := gorm.Open(mysql.Open(connStr))
+	db, err := openMySQL(target)
 	if err != nil {
 		return nil, err
 	}
@@ -36,18 +47,53 @@ func NewMySQLImplementation(input *config.Input, target *config.Target, verboseM
 	return &mySQLImplementation{input, target, db, verboseMode, procHook}, nil
 }
 
-func (impl *mySQLImplementation) Process(data [][]string) error {
-	switch impl.target.Mode {
-	case config.TargetModeUpdate:
-		return impl.performUpdate(data)
-	default:
-		return impl.performUpsert(data)
+func (impl *mySQLImplementation) Process(data [][]string, nulls [][]bool) (*Result, error) {
+	return impl.execute(impl.db, data, nulls)
+}
+
+// execute the generated queries inside a transaction which is always rolled back
+func (impl *mySQLImplementation) DryRun(data [][]string, nulls [][]bool, _ []int) (*Result, error) {
+	tx := impl.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
 	}
+
+	defer tx.Rollback()
+
+	return impl.execute(tx, data, nulls)
 }
 
-func (impl *mySQLImplementation) DryRun(data [][]string) error {
-	// TODO: implement dry run
-	return fmt.Errorf("not implemented")
+func (impl *mySQLImplementation) ClassifyError(err error) ErrorClass {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mySQLErrLockWaitTimeout, mySQLErrDeadlock:
+			return ErrorClassRetryable
+		case mySQLErrTooManyConnections, mySQLErrServerShutdown, mySQLErrServerGone, mySQLErrServerLost:
+			return ErrorClassConnection
+		}
+
+		return ErrorClassPermanent
+	}
+
+	if errors.Is(err, mysqldriver.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) || isConnectionError(err) {
+		return ErrorClassConnection
+	}
+
+	return ErrorClassPermanent
+}
+
+// replace connection pool, the old pool is closed after the new one is ready
+func (impl *mySQLImplementation) Reconnect() error {
+	db, err := openMySQL(impl.target)
+	if err != nil {
+		return err
+	}
+
+	impl.Close()
+	impl.db = db
+
+	return nil
 }
 
 func (impl *mySQLImplementation) Close() {
@@ -69,18 +115,32 @@ func (impl *mySQLImplementation) getColumnNames() []string {
 	return columnNames
 }
 
-func (impl *mySQLImplementation) performUpdate(data [][]string) error {
+func (impl *mySQLImplementation) execute(db *gorm.DB, data [][]string, nulls [][]bool) (res *Result, err error) {
+	res = &Result{TotalRows: len(data), Values: []map[string]string{}}
+
+	switch impl.target.Mode {
+	case config.TargetModeUpdate:
+		err = impl.performUpdate(db, data, nulls, res)
+	default:
+		err = impl.performUpsert(db, data, nulls, res)
+	}
+
+	return res, err
+}
+
+func (impl *mySQLImplementation) performUpdate(db *gorm.DB, data [][]string, nulls [][]bool, res *Result) error {
 	updatedRows := []map[string]any{}
 	whereParams := [][]any{}
 
 	for i := range data {
 		row := map[string]any{}
 		param := []any{}
+		values := map[string]string{}
 
 		for j := range impl.target.Fields {
 			f := &impl.target.Fields[j]
 
-			cval, err := impl.parseColumnValue(f, data[i])
+			cval, err := impl.parseColumnValue(f, data[i], getRowNulls(nulls, i))
 			if err != nil {
 				return err
 			}
@@ -90,10 +150,13 @@ func (impl *mySQLImplementation) performUpdate(data [][]string) error {
 			} else {
 				row[f.Name] = cval
 			}
+
+			values[f.Name] = formatColumnValue(cval)
 		}
 
 		updatedRows = append(updatedRows, row)
 		whereParams = append(whereParams, param)
+		res.Values = append(res.Values, values)
 	}
 
 	// build where clause and updated column names manually
@@ -114,37 +177,42 @@ func (impl *mySQLImplementation) performUpdate(data [][]string) error {
 	whereClause := strings.Join(clausePlaceholders, " AND ")
 
 	for i := range data {
-		err := impl.db.
+		query := db.
 			Table(impl.target.DataName).
 			Select(columnNames).
 			Where(whereClause, whereParams[i]...).
-			Updates(updatedRows[i]).
-			Error
+			Updates(updatedRows[i])
 
-		if err != nil {
-			return err
+		if query.Error != nil {
+			return query.Error
 		}
+
+		res.AffectedRows += query.RowsAffected
 	}
 
 	return nil
 }
 
-func (impl *mySQLImplementation) performUpsert(data [][]string) error {
+func (impl *mySQLImplementation) performUpsert(db *gorm.DB, data [][]string, nulls [][]bool, res *Result) error {
 	newRows := []map[string]any{}
 	for i := range data {
 		row := map[string]any{}
+		values := map[string]string{}
+
 		for j := range impl.target.Fields {
 			f := &impl.target.Fields[j]
 
-			cval, err := impl.parseColumnValue(f, data[i])
+			cval, err := impl.parseColumnValue(f, data[i], getRowNulls(nulls, i))
 			if err != nil {
 				return err
 			}
 
 			row[f.Name] = cval
+			values[f.Name] = formatColumnValue(cval)
 		}
 
 		newRows = append(newRows, row)
+		res.Values = append(res.Values, values)
 	}
 
 	// log generated values
@@ -152,7 +220,7 @@ func (impl *mySQLImplementation) performUpsert(data [][]string) error {
 		fmt.Printf("[Target MySQL ID: %s] %s\n", impl.target.ID, util.Jsonify(newRows))
 	}
 
-	query := impl.db.
+	query := db.
 		Table(impl.target.DataName).
 		Select(impl.getColumnNames())
 
@@ -162,10 +230,13 @@ func (impl *mySQLImplementation) performUpsert(data [][]string) error {
 		query = query.Clauses(upsertHandler)
 	}
 
-	return query.Create(newRows).Error
+	query = query.Create(newRows)
+	res.AffectedRows = query.RowsAffected
+
+	return query.Error
 }
 
-func (impl *mySQLImplementation) parseColumnValue(field *config.TargetField, rowData []string) (any, error) {
+func (impl *mySQLImplementation) parseColumnValue(field *config.TargetField, rowData []string, rowNulls []bool) (any, error) {
 	if field == nil {
 		return nil, fmt.Errorf("missing target field config")
 	}
@@ -197,6 +268,18 @@ func (impl *mySQLImplementation) parseColumnValue(field *config.TargetField, row
 		return val, nil
 	}
 
+	// null input value is resolved like empty value, but it's null by default instead of empty string
+	if impl.isNullReference(fieldValue, field.References, rowNulls) {
+		fieldValue = string(NilValue)
+		if field.ValueIfEmpty != nil {
+			fieldValue = *field.ValueIfEmpty
+		}
+
+		if val, ok := toSQLNull(fieldValue); ok {
+			return val, nil
+		}
+	}
+
 	for _, ref := range field.References {
 		refID := util.RemoveToken(ref)
 
@@ -236,6 +319,16 @@ func (impl *mySQLImplementation) parseColumnValue(field *config.TargetField, row
 	return parseStringToType(field.Type, fieldValue)
 }
 
+// true if value is a single reference to an input value which is null
+func (impl *mySQLImplementation) isNullReference(value string, references []string, rowNulls []bool) bool {
+	if rowNulls == nil || len(references) != 1 || value != references[0] {
+		return false
+	}
+
+	i, ok := impl.input.FieldsIndexMap[util.RemoveToken(references[0])]
+	return ok && i < len(rowNulls) && rowNulls[i]
+}
+
 func (impl *mySQLImplementation) constructUpsertHandler() (res clause.OnConflict) {
 	cols := []clause.Column{}
 	for _, f := range impl.target.UniqueConstraints {
@@ -253,6 +346,20 @@ func (impl *mySQLImplementation) constructUpsertHandler() (res clause.OnConflict
 	return res
 }
 
+func openMySQL(target *config.Target) (*gorm.DB, error) {
+	connStr := "%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local"
+	connStr = fmt.Sprintf(connStr, target.Username, target.Password, target.Host, target.Port, target.Name)
+	return gorm.Open(mysql.Open(connStr))
+}
+
+func getRowNulls(nulls [][]bool, i int) []bool {
+	if nulls == nil {
+		return nil
+	}
+
+	return nulls[i]
+}
+
 func toSQLNull(val string) (any, bool) {
 	if Function(val) == NilValue {
 		return gorm.Expr(NullExpr), true
@@ -269,6 +376,18 @@ func toSQLCurrentTimestamp(val string) (any, bool) {
 	return val, false
 }
 
+// format parsed column value for logging and result file
+func formatColumnValue(val any) string {
+	switch v := val.(type) {
+	case clause.Expr:
+		return v.SQL
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 func parseStringToType(valType config.ValueType, stringVal string) (any, error) {
 	switch valType {
 	case config.ValueTypeBoolean:
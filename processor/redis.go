@@ -2,6 +2,7 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -18,27 +19,57 @@ const (
 	TTLColumn   = "ttl"
 )
 
+// prefix of transient error replies from redis server
+var redisRetryablePrefixes = []string{"LOADING", "BUSY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN"}
+
 type redisImplementation struct {
 	input       *config.Input
 	target      *config.Target
 	client      *redis.Client
 	verboseMode bool
+	dryRunKeys  map[string]int // map of key to the first line which use it during dry run
 }
 
 func NewRedisImplementation(input *config.Input, target *config.Target, verboseMode bool) (*redisImplementation, error) {
-	host := fmt.Sprintf("%s:%d", target.Host, target.Port)
+	client, err := openRedis(target)
+	if err != nil {
+		return nil, err
+	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     host,
-		Password: target.Password,
-	})
+	return &redisImplementation{input, target, client, verboseMode, map[string]int{}}, nil
+}
 
-	_, err := client.Ping(context.Background()).Result()
+func (impl *redisImplementation) ClassifyError(err error) ErrorClass {
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		msg := redisErr.Error()
+		for _, prefix := range redisRetryablePrefixes {
+			if strings.HasPrefix(msg, prefix) {
+				return ErrorClassRetryable
+			}
+		}
+
+		return ErrorClassPermanent
+	}
+
+	if isConnectionError(err) {
+		return ErrorClassConnection
+	}
+
+	return ErrorClassPermanent
+}
+
+// replace client, the old client is closed after the new one is ready
+func (impl *redisImplementation) Reconnect() error {
+	client, err := openRedis(impl.target)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &redisImplementation{input, target, client, verboseMode}, nil
+	impl.Close()
+	impl.client = client
+
+	return nil
 }
 
 func (impl *redisImplementation) Close() {
@@ -47,18 +78,103 @@ func (impl *redisImplementation) Close() {
 	}
 }
 
-func (impl *redisImplementation) DryRun(data [][]string) error {
-	// TODO: implement dry run
-	return fmt.Errorf("not implemented")
-}
+// resolve and validate every row, then check existing keys using read-only commands
+func (impl *redisImplementation) DryRun(data [][]string, _ [][]bool, rows []int) (*Result, error) {
+	if err := impl.validateFields(); err != nil {
+		return nil, err
+	}
 
-func (impl *redisImplementation) Process(data [][]string) error {
-	fields := impl.target.Fields
+	newRows := impl.constructRows(data)
+	res := &Result{TotalRows: len(newRows), AffectedRows: int64(len(newRows)), Warnings: []string{}, Values: newRows}
+
+	invalidTTLs := []string{}
+	for i, row := range newRows {
+		line := rows[i] + 1
+		key := row[KeyColumn]
+
+		if _, err := parseTTL(row[TTLColumn]); err != nil {
+			invalidTTLs = append(invalidTTLs, fmt.Sprintf("line %d: %s", line, err))
+		}
+
+		// same line is checked again when a failed batch is retried row by row
+		if prevLine, exists := impl.dryRunKeys[key]; exists && prevLine != line {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("line %d: key %s already used at line %d", line, key, prevLine))
+		} else {
+			impl.dryRunKeys[key] = line
+		}
+	}
+
+	if len(invalidTTLs) > 0 {
+		return res, fmt.Errorf("%s", strings.Join(invalidTTLs, "; "))
+	}
+
+	ctx := context.Background()
+	pipe := impl.client.Pipeline()
+
+	existsCmds := []*redis.IntCmd{}
+	ttlCmds := []*redis.DurationCmd{}
+	for _, row := range newRows {
+		existsCmds = append(existsCmds, pipe.Exists(ctx, row[KeyColumn]))
+		ttlCmds = append(ttlCmds, pipe.TTL(ctx, row[KeyColumn]))
+	}
 
+	if _, err := pipe.Exec(ctx); err != nil {
+		return res, err
+	}
+
+	for i, row := range newRows {
+		if existsCmds[i].Val() == 0 {
+			continue
+		}
+
+		ttl := "no expiration"
+		if remaining := ttlCmds[i].Val(); remaining > 0 {
+			ttl = remaining.String()
+		}
+
+		res.Warnings = append(res.Warnings, fmt.Sprintf("line %d: key %s already exists (ttl: %s) and would be overwritten", rows[i]+1, row[KeyColumn], ttl))
+	}
+
+	return res, nil
+}
+
+// redis doesn't have null value, so null input value is used as empty string
+func (impl *redisImplementation) Process(data [][]string, _ [][]bool) (*Result, error) {
 	if err := impl.validateFields(); err != nil {
-		return err
+		return nil, err
+	}
+
+	newRows := impl.constructRows(data)
+	res := &Result{TotalRows: len(newRows), Values: newRows}
+
+	// log generated values
+	if impl.verboseMode {
+		fmt.Printf("[Target Redis ID: %s] %s\n", impl.target.ID, util.Jsonify(newRows))
+	}
+
+	for _, row := range newRows {
+		key := row[KeyColumn]
+		value := row[ValueColumn]
+
+		ttl, err := parseTTL(row[TTLColumn])
+		if err != nil {
+			return res, err
+		}
+
+		err = impl.client.Set(context.Background(), key, value, ttl).Err()
+		if err != nil {
+			return res, err
+		}
+
+		res.AffectedRows += 1
 	}
 
+	return res, nil
+}
+
+func (impl *redisImplementation) constructRows(data [][]string) []map[string]string {
+	fields := impl.target.Fields
+
 	newRows := []map[string]string{}
 	for i := range data {
 		row := map[string]string{}
@@ -84,28 +200,7 @@ func (impl *redisImplementation) Process(data [][]string) error {
 		newRows = append(newRows, row)
 	}
 
-	// log generated values
-	if impl.verboseMode {
-		fmt.Printf("[Target Redis ID: %s] %s\n", impl.target.ID, util.Jsonify(newRows))
-	}
-
-	for _, row := range newRows {
-		key := row[KeyColumn]
-		value := row[ValueColumn]
-		rawTTL := row[TTLColumn]
-
-		ttl, err := strconv.ParseInt(rawTTL, 10, 64)
-		if err != nil {
-			return fmt.Errorf("unknown TTL value: %s", err)
-		}
-
-		err = impl.client.Set(context.Background(), key, value, time.Duration(ttl)*time.Second).Err()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return newRows
 }
 
 func (impl *redisImplementation) validateFields() error {
@@ -126,3 +221,30 @@ func (impl *redisImplementation) validateFields() error {
 
 	return nil
 }
+
+func openRedis(target *config.Target) (*redis.Client, error) {
+	host := fmt.Sprintf("%s:%d", target.Host, target.Port)
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     host,
+		Password: target.Password,
+	})
+
+	_, err := client.Ping(context.Background()).Result()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// TTL is defined in seconds
+func parseTTL(rawTTL string) (time.Duration, error) {
+	ttl, err := strconv.ParseInt(rawTTL, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unknown TTL value: %s", err)
+	}
+
+	return time.Duration(ttl) * time.Second, nil
+}
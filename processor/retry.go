@@ -0,0 +1,81 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+type ErrorClass int
+
+// returned when waiting for the next attempt is aborted by interruption, the batch is not completely executed
+var ErrRetryAborted = errors.New("retry aborted by interruption")
+
+// known error classes
+const (
+	ErrorClassPermanent  ErrorClass = iota // retrying will not help, e.g. invalid value or constraint violation
+	ErrorClassRetryable                    // transient error, e.g. deadlock or server is still loading data
+	ErrorClassConnection                   // connection is dead, retry after reconnecting
+)
+
+// execute function until it succeed, returns permanent error or error of the last attempt. Rows are 0 based
+// index of every executed row in input
+func (proc *Processor) withRetry(rows []int, execute func() (*Result, error)) (res *Result, err error) {
+	policy := proc.target.Retry
+
+	for attempt := 1; ; attempt++ {
+		res, err = execute()
+		if err == nil {
+			return res, nil
+		}
+
+		class := proc.impl.ClassifyError(err)
+		if class == ErrorClassPermanent || attempt >= policy.MaxAttempts {
+			return res, err
+		}
+
+		backoff := getBackoff(attempt, *policy.InitialBackoff, *policy.MaxBackoff, policy.Multiplier, *policy.Jitter)
+		fmt.Printf("[Target ID: %s] batch index %d (line %d to %d) attempt %d of %d failed, retrying in %s: %s\n", proc.ID, rows[0], rows[0]+1, rows[len(rows)-1]+1, attempt, policy.MaxAttempts, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-proc.interrupted:
+			return res, fmt.Errorf("%w: %s", ErrRetryAborted, err)
+		}
+
+		if class == ErrorClassConnection {
+			if connErr := proc.impl.Reconnect(); connErr != nil {
+				fmt.Printf("[Target ID: %s] unable to reconnect: %s\n", proc.ID, connErr)
+			}
+		}
+	}
+}
+
+// exponential backoff in ms with random jitter, attempt start from 1. Jittered backoff never exceeds max
+func getBackoff(attempt, initial, max int, multiplier, jitter float64) time.Duration {
+	backoff := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	backoff = math.Min(backoff, float64(max))
+	backoff += backoff * jitter * (rand.Float64()*2 - 1)
+	backoff = math.Max(math.Min(backoff, float64(max)), 0)
+
+	return time.Duration(backoff) * time.Millisecond
+}
+
+// network errors which are shared by every implementation
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempt    int
+		initial    int
+		max        int
+		multiplier float64
+		jitter     float64
+		min        time.Duration
+		maxWant    time.Duration
+	}{
+		{"first attempt", 1, 100, 1000, 2, 0, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"exponential", 3, 100, 1000, 2, 0, 400 * time.Millisecond, 400 * time.Millisecond},
+		{"capped by max", 10, 100, 1000, 2, 0, time.Second, time.Second},
+		{"zero initial backoff", 5, 0, 1000, 2, 0.5, 0, 0},
+		{"jitter range", 2, 100, 1000, 2, 0.5, 100 * time.Millisecond, 300 * time.Millisecond},
+		{"jitter never exceeds max", 10, 100, 1000, 2, 1, 0, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				backoff := getBackoff(tt.attempt, tt.initial, tt.max, tt.multiplier, tt.jitter)
+				if backoff < tt.min || backoff > tt.maxWant {
+					t.Fatalf("backoff %s is not between %s and %s", backoff, tt.min, tt.maxWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package uploader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exit code when the process is stopped by SIGINT or SIGTERM, same as shell convention for SIGINT
+const ExitCodeInterrupted = 130
+
+var ErrInterrupted = errors.New("interrupted")
+
+// trap SIGINT and SIGTERM, the returned function must be called to release the trap. After the first
+// signal is received the trap is released, so the next signal will terminate the process immediately
+func (up *Uploader) trapSignals() (release func()) {
+	signals := make(chan os.Signal, 1)
+	interrupted := make(chan struct{})
+	done := make(chan struct{})
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			fmt.Printf("[Signal] received %s, stopping after the current batch. Send it again to force exit\n", sig)
+			close(interrupted)
+		case <-done:
+		}
+	}()
+
+	up.interrupted = interrupted
+	for i := range up.Processors {
+		up.Processors[i].SetInterrupt(interrupted)
+	}
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+func (up *Uploader) isInterrupted() bool {
+	select {
+	case <-up.interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// save checkpoint and finish hook, so the run can be resumed later
+func (up *Uploader) stopInterrupted() error {
+	if !up.Config.Args.DryRunFlag {
+		err := up.checkPointStore.Save(up.CheckPoint, ErrInterrupted)
+		if err != nil {
+			fmt.Printf("[Check Point] unable to save checkpoint: %s\n", err)
+		} else {
+			fmt.Printf("[Check Point] progress saved to %s, use --resume to continue\n", up.checkPointStore.Location())
+		}
+	}
+
+	if up.procHook != nil {
+		if err := up.procHook.Finish(); err != nil {
+			fmt.Printf("[Hook] unable to finish: %s\n", err)
+		}
+	}
+
+	return ErrInterrupted
+}
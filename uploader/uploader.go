@@ -1,12 +1,17 @@
 package uploader
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
+	"github.com/ridwanadhip/universal-uploader/checkpoint"
 	"github.com/ridwanadhip/universal-uploader/config"
 	"github.com/ridwanadhip/universal-uploader/hook"
 	"github.com/ridwanadhip/universal-uploader/input"
+	"github.com/ridwanadhip/universal-uploader/output"
 	"github.com/ridwanadhip/universal-uploader/processor"
 	"github.com/ridwanadhip/universal-uploader/util"
 )
@@ -17,14 +22,51 @@ type Uploader struct {
 	InputParser input.Parser
 	Processors  []processor.Processor
 	CheckPoint  *config.CheckPoint
+	Output      output.Writer
+	FailedRows  input.FailedRowsWriter // nil if failed rows file is disabled
 	procHook    hook.ProcessorHook
+
+	checkPointStore checkpoint.Store
+	stats           map[string]*targetStats
+	totalFailedRows int
+	seekedRows      int // rows before the position where input is seeked when resumed, they are never read
+	interrupted     <-chan struct{}
+}
+
+// total of processed and failed rows of a target in current session
+type targetStats struct {
+	processedRows int
+	failedRows    int
+}
+
+func NewUploader(args *config.Args, procHook hook.ProcessorHook) (*Uploader, error) {
+	return newUploader(args, procHook, input.NewParser)
+}
+
+// library entry point which reads input from r instead of input file, input path of args is ignored
+func NewUploaderFromReader(args *config.Args, procHook hook.ProcessorHook, r io.Reader) (*Uploader, error) {
+	return newUploader(args, procHook, func(cfg *config.Config) (input.Parser, error) {
+		return input.NewReaderParser(cfg, r)
+	})
 }
 
-func NewUploader(args *config.Args, procHook hook.ProcessorHook) (res *Uploader, err error) {
+// library entry point which reads input from rows which are already in memory or generated by the caller,
+// e.g. input.NewSliceRowSource. Input path of args is ignored
+func NewUploaderFromRows(args *config.Args, procHook hook.ProcessorHook, source input.RowSource) (*Uploader, error) {
+	return newUploader(args, procHook, func(cfg *config.Config) (input.Parser, error) {
+		return input.NewRowSourceParser(cfg, source)
+	})
+}
+
+func newUploader(args *config.Args, procHook hook.ProcessorHook, newInputParser func(cfg *config.Config) (input.Parser, error)) (res *Uploader, err error) {
 	if args.ConfigType == "" {
 		args.ConfigType = string(config.DefaultConfigType)
 	}
 
+	if args.CheckPointPath == "" {
+		args.CheckPointPath = config.DefaultCheckPointPath
+	}
+
 	cfgParser, err := config.NewParser(args)
 	if err != nil {
 		return nil, err
@@ -35,7 +77,15 @@ func NewUploader(args *config.Args, procHook hook.ProcessorHook) (res *Uploader,
 		return nil, err
 	}
 
-	inputParser, err := input.NewParser(cfg)
+	inputParser, err := newInputParser(cfg)
+
+	// temporary copy of input stream is removed by closing the parser
+	defer func() {
+		if err != nil {
+			inputParser.Close()
+		}
+	}()
+
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +95,14 @@ func NewUploader(args *config.Args, procHook hook.ProcessorHook) (res *Uploader,
 	}
 
 	procs := []processor.Processor{}
+	defer func() {
+		if err != nil {
+			for i := range procs {
+				procs[i].Close()
+			}
+		}
+	}()
+
 	for i := range cfg.Targets {
 		targetID := cfg.Targets[i].ID
 		proc, err := processor.NewProcessor(cfg, targetID, procHook)
@@ -55,22 +113,95 @@ func NewUploader(args *config.Args, procHook hook.ProcessorHook) (res *Uploader,
 		procs = append(procs, proc)
 	}
 
+	checkPoint, err := cfg.NewCheckPoint()
+	if err != nil {
+		return nil, err
+	}
+
+	checkPointStore, err := checkpoint.NewStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open checkpoint store: %s", err)
+	}
+
+	// dry run never saves checkpoint, so it doesn't need the lock
+	if !cfg.Args.DryRunFlag {
+		if err := checkPointStore.Lock(); err != nil {
+			checkPointStore.Close()
+			return nil, err
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			checkPointStore.Close()
+		}
+	}()
+
 	res = &Uploader{
 		Args:        args,
 		Config:      cfg,
 		InputParser: inputParser,
 		Processors:  procs,
-		CheckPoint:  cfg.NewCheckPoint(),
+		CheckPoint:  checkPoint,
 		procHook:    procHook,
+
+		checkPointStore: checkPointStore,
+		stats:           map[string]*targetStats{},
+	}
+
+	for i := range cfg.Targets {
+		res.stats[cfg.Targets[i].ID] = &targetStats{}
 	}
 
 	if cfg.Args.ResumeFlag {
-		_, err := res.CheckPoint.Load(args.CheckPointPath)
+		saved := &config.CheckPoint{}
+		exists, err := checkPointStore.Load(saved)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			if err := saved.Verify(checkPoint); err != nil {
+				if !cfg.Args.ForceResumeFlag {
+					return nil, fmt.Errorf("%s, use --force-resume to ignore", err)
+				}
+
+				fmt.Printf("[Check Point] %s, ignored because of --force-resume\n", err)
+			}
+
+			if saved.BatchSize != 0 && saved.BatchSize != cfg.BatchSize {
+				fmt.Printf("[Check Point] batch size is changed from %d to %d\n", saved.BatchSize, cfg.BatchSize)
+			}
+
+			checkPoint.Resume(saved)
+
+			// byte offset is only valid if the input file and the way it's read are not changed
+			if saved.InputHash == checkPoint.InputHash && saved.InputConfigHash == checkPoint.InputConfigHash {
+				if err := res.seekInput(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// output files are created after the lock is held, so a concurrent run can't truncate them. Output
+	// writer is created after input parser because input parser inject missing fields to config
+	if cfg.FailedRows.Enable {
+		res.FailedRows, err = inputParser.NewFailedRowsWriter(cfg.FailedRows.Path)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	res.Output, err = output.NewWriter(cfg)
+	if err != nil {
+		if res.FailedRows != nil {
+			res.FailedRows.Close()
+		}
+
+		return nil, err
+	}
+
 	return res, nil
 }
 
@@ -81,7 +212,7 @@ func (up *Uploader) Run() error {
 	}
 
 	if up.CheckPoint.IsLoaded() {
-		fmt.Printf("[Check Point] load last checkpoint from file %s\n", up.Config.Args.CheckPointPath)
+		fmt.Printf("[Check Point] load last checkpoint from %s\n", up.checkPointStore.Location())
 
 		if up.Config.Args.VerboseModeFlag {
 			fmt.Printf("[Check Point] %s\n", util.Jsonify(up.CheckPoint))
@@ -95,7 +226,20 @@ func (up *Uploader) Run() error {
 		}
 	}
 
+	// export result file even if the run is stopped by an error
+	defer up.closeOutput()
+	defer up.closeFailedRows()
+
+	release := up.trapSignals()
+	defer release()
+
+	up.writeSeekedOutput()
+
 	for {
+		if up.isInterrupted() {
+			return up.stopInterrupted()
+		}
+
 		batch, exists, err := up.InputParser.NextBatch()
 		if err != nil {
 			return err
@@ -110,39 +254,39 @@ func (up *Uploader) Run() error {
 			fmt.Printf("[Config] %s\n", util.Jsonify(batch))
 		}
 
-		for _, proc := range up.Processors {
-			targetID := proc.ID
-			start := batch.Index + 1
-			stop := batch.Index + len(batch.Data)
+		up.writeRejectedRows(batch)
 
-			// skip if already processed in previous sesssion
-			// TODO: handle changed batch size value when resume
-			// TODO: handle changed target file configuration
-			if up.CheckPoint.IsLoaded() && batch.Index < up.CheckPoint.Progress[targetID] {
-				fmt.Printf("[Target ID: %s] line %d to %d already processed in previous session\n", targetID, start, stop)
-				continue
-			}
+		// every row of batch is skipped by input config
+		if len(batch.Data) == 0 {
+			continue
+		}
 
-			up.CheckPoint.Progress[targetID] = batch.Index
+		// map of 0 based row index to error messages from every target
+		failures := map[int][]string{}
 
-			err := proc.Process(batch.Data, batch.Index)
-			if err != nil {
-				cpErr := up.CheckPoint.Save(up.Config.Args.CheckPointPath, err)
-				if cpErr != nil {
-					fmt.Printf("[Target ID: %s] unable to save checkpoint: %s\n", targetID, cpErr)
-				}
-
-				return fmt.Errorf("[Target ID: %s] error: %s", targetID, err)
+		for i := range up.Processors {
+			// remaining targets will continue from this batch when resumed
+			if up.isInterrupted() {
+				up.writeFailedRows(batch, failures)
+				return up.stopInterrupted()
 			}
 
-			fmt.Printf("[Target ID: %s] successfully uploaded line %d to %d\n", targetID, start, stop)
+			if err := up.processBatch(&up.Processors[i], batch, failures); err != nil {
+				up.writeFailedRows(batch, failures)
+				return err
+			}
 		}
 
+		up.writeFailedRows(batch, failures)
+
 		if up.Config.Args.VerboseModeFlag {
 			fmt.Printf("[Delay] %d ms\n", up.Config.Delay)
 		}
 
-		time.Sleep(time.Duration(up.Config.Delay) * time.Millisecond)
+		select {
+		case <-time.After(time.Duration(up.Config.Delay) * time.Millisecond):
+		case <-up.interrupted:
+		}
 	}
 
 	if up.procHook != nil {
@@ -152,15 +296,360 @@ func (up *Uploader) Run() error {
 		}
 	}
 
-	// TODO: implement exporting result file
+	if up.Config.Args.DryRunFlag {
+		failedRows := 0
+		for _, stats := range up.stats {
+			failedRows += stats.failedRows
+		}
+
+		if failedRows > 0 {
+			return fmt.Errorf("[Dry Run] %d rows failed, nothing was committed", failedRows)
+		}
+
+		fmt.Printf("[Dry Run] all batches passed, nothing was committed\n")
+	}
 
 	return nil
 }
 
 func (up *Uploader) Close() {
 	up.InputParser.Close()
+	up.Output.Close()
+
+	if up.FailedRows != nil {
+		up.FailedRows.Close()
+		up.FailedRows = nil
+	}
 
 	for _, proc := range up.Processors {
 		proc.Close()
 	}
+
+	if up.checkPointStore != nil {
+		up.checkPointStore.Close()
+		up.checkPointStore = nil
+	}
+}
+
+// process a batch for a single target, returns error if the run must be stopped
+func (up *Uploader) processBatch(proc *processor.Processor, batch *input.Batch, failures map[int][]string) error {
+	targetID := proc.ID
+
+	// skip rows which already processed in previous sesssion, progress is recorded as total of rows
+	// so the batch is split if batch size is changed
+	if processed := getProcessedRows(batch, up.CheckPoint.Progress[targetID]); up.CheckPoint.IsLoaded() && processed > 0 {
+		skipped := batch.Slice(0, processed)
+
+		fmt.Printf("[Target ID: %s] %s already processed in previous session\n", targetID, skipped.DescribeLines(0, processed-1))
+		up.writeOutput(targetID, skipped, output.RowStatusSkipped, nil, nil)
+
+		if processed == len(batch.Data) {
+			return nil
+		}
+
+		batch = batch.Slice(processed, len(batch.Data))
+	}
+
+	rows := batch.RowIndexes()
+	lines := batch.DescribeLines(0, len(batch.Data)-1)
+	stats := up.stats[targetID]
+
+	// validate batch without committing anything, keep going to report every failed batch
+	if up.Config.Args.DryRunFlag {
+		res, err := proc.DryRun(batch.Data, batch.Nulls, rows)
+		if errors.Is(err, processor.ErrRetryAborted) {
+			return up.stopInterrupted()
+		}
+
+		failedRows, _ := up.recordResult(targetID, batch, output.RowStatusValidated, res, err, failures)
+
+		stats.processedRows += len(batch.Data)
+		stats.failedRows += failedRows
+
+		if err != nil {
+			fmt.Printf("[Target ID: %s] [Dry Run] %s failed: %s\n", targetID, lines, err)
+			return nil
+		}
+
+		for _, warning := range res.Warnings {
+			fmt.Printf("[Target ID: %s] [Dry Run] %s\n", targetID, warning)
+		}
+
+		if failedRows > 0 {
+			fmt.Printf("[Target ID: %s] [Dry Run] %s has %d failed rows, %d of %d rows affected\n", targetID, lines, failedRows, res.AffectedRows, res.TotalRows)
+			return nil
+		}
+
+		fmt.Printf("[Target ID: %s] [Dry Run] %s passed, %d of %d rows affected\n", targetID, lines, res.AffectedRows, res.TotalRows)
+		return nil
+	}
+
+	up.setProgress(targetID, batch, 0)
+
+	// batch is not completely executed, so it will be executed again when resumed
+	res, err := proc.Process(batch.Data, batch.Nulls, rows)
+	if errors.Is(err, processor.ErrRetryAborted) {
+		fmt.Printf("[Target ID: %s] %s aborted: %s\n", targetID, lines, err)
+
+		// rows executed row by row before the aborted row are already committed
+		if res != nil && len(res.RowErrors) > 0 {
+			executed := batch.Slice(0, len(res.RowErrors))
+			failedRows, _ := up.recordResult(targetID, executed, output.RowStatusOK, res, nil, failures)
+
+			stats.processedRows += len(executed.Data)
+			stats.failedRows += failedRows
+			up.setProgress(targetID, batch, len(executed.Data))
+		}
+
+		return up.stopInterrupted()
+	}
+
+	failedRows, lastErr := up.recordResult(targetID, batch, output.RowStatusOK, res, err, failures)
+
+	stats.processedRows += len(batch.Data)
+	stats.failedRows += failedRows
+
+	// rows of batch executed row by row are already committed, so resume from next batch
+	if err == nil {
+		up.setProgress(targetID, batch, len(batch.Data))
+	}
+
+	if failedRows == 0 {
+		fmt.Printf("[Target ID: %s] successfully uploaded %s\n", targetID, lines)
+	} else {
+		fmt.Printf("[Target ID: %s] uploaded %s with %d failed rows: %s\n", targetID, lines, failedRows, lastErr)
+	}
+
+	target := up.Config.TargetMap[targetID]
+	if !target.ErrorBudget.IsExceeded(stats.failedRows, stats.processedRows) {
+		return nil
+	}
+
+	reason := lastErr
+	if target.MaxErrors != "" {
+		reason = fmt.Errorf("max errors %s exceeded, %d of %d rows failed, last error: %s", target.MaxErrors, stats.failedRows, stats.processedRows, lastErr)
+	}
+
+	cpErr := up.checkPointStore.Save(up.CheckPoint, reason)
+	if cpErr != nil {
+		fmt.Printf("[Target ID: %s] unable to save checkpoint: %s\n", targetID, cpErr)
+	}
+
+	return fmt.Errorf("[Target ID: %s] error: %s", targetID, reason)
+}
+
+// record progress as total of processed rows, pos is the position of the next row in batch
+func (up *Uploader) setProgress(targetID string, batch *input.Batch, pos int) {
+	row := batch.RowIndex(pos)
+
+	// file of the row is still useful without offset, because the files before it can be skipped
+	offset, exists := batch.GetOffset(pos)
+	if !exists && batch.File == "" {
+		up.CheckPoint.Progress[targetID] = row
+		delete(up.CheckPoint.Positions, targetID)
+		return
+	}
+
+	if !exists {
+		offset = -1
+	}
+
+	up.CheckPoint.SetProgress(targetID, config.InputPosition{
+		Row:     row,
+		Offset:  offset,
+		File:    batch.File,
+		FileRow: row - batch.FileStart,
+	})
+}
+
+// skip processed rows by seeking input file directly, instead of reading them again
+func (up *Uploader) seekInput() error {
+	pos, exists := up.CheckPoint.GetResumePosition()
+	if !exists || pos.Row == 0 {
+		return nil
+	}
+
+	seeked, err := up.InputParser.SeekRow(pos)
+	if err != nil {
+		return err
+	}
+
+	// only the file of the row is seeked if its byte offset is unknown, so rows before it in the file are
+	// read again
+	if seeked {
+		up.seekedRows = up.InputParser.GetCurrentIndex()
+	}
+
+	switch {
+	case !seeked:
+	case pos.File != "" && pos.Offset < 0:
+		fmt.Printf("[Check Point] continue reading input file %s from line %d\n", pos.File, pos.FileRow+1)
+	case pos.File != "":
+		fmt.Printf("[Check Point] continue reading input file %s from line %d (byte offset %d)\n", pos.File, pos.FileRow+1, pos.Offset)
+	default:
+		fmt.Printf("[Check Point] continue reading input file from line %d (byte offset %d)\n", pos.Row+1, pos.Offset)
+	}
+
+	return nil
+}
+
+// rows before the position where input is seeked are not read again, so their result is recorded by
+// line without reading them
+func (up *Uploader) writeSeekedOutput() {
+	if up.seekedRows == 0 {
+		return
+	}
+
+	for i := range up.Processors {
+		targetID := up.Processors[i].ID
+		fmt.Printf("[Target ID: %s] line 1 to %d already processed in previous session\n", targetID, up.seekedRows)
+
+		if !up.Output.IsEnabled() {
+			continue
+		}
+
+		for row := 0; row < up.seekedRows; row++ {
+			if err := up.Output.WriteRow(targetID, row+1, output.RowStatusSkipped, nil, nil); err != nil {
+				fmt.Printf("[Output] unable to write result of line 1 to %d: %s\n", up.seekedRows, err)
+				break
+			}
+		}
+	}
+}
+
+// failing to record result must not interrupt the upload process, so only log the error
+func (up *Uploader) writeOutput(targetID string, batch *input.Batch, status output.RowStatus, res *processor.Result, batchErr error) {
+	var values []map[string]string
+	if res != nil {
+		values = res.Values
+	}
+
+	err := up.Output.WriteBatch(targetID, batch.RowIndexes(), status, values, batchErr)
+	if err != nil {
+		fmt.Printf("[Output] unable to write result of %s: %s\n", batch.DescribeLines(0, len(batch.Data)-1), err)
+	}
+}
+
+func (up *Uploader) closeOutput() {
+	if !up.Output.IsEnabled() {
+		return
+	}
+
+	if err := up.Output.Close(); err != nil {
+		fmt.Printf("[Output] unable to export result file: %s\n", err)
+		return
+	}
+
+	fmt.Printf("[Output] result file exported to %s\n", up.Config.Output.Path)
+}
+
+// write original rows of every failure in a batch, rows which failed in many targets are written once
+func (up *Uploader) writeFailedRows(batch *input.Batch, failures map[int][]string) {
+	if up.FailedRows == nil || len(failures) == 0 {
+		return
+	}
+
+	for pos := range batch.Data {
+		index := batch.RowIndex(pos)
+		if _, exists := failures[index]; !exists {
+			continue
+		}
+
+		err := up.FailedRows.Write(batch.Raw[pos], strings.Join(failures[index], "; "))
+		if err != nil {
+			fmt.Printf("[Failed Rows] unable to write %s: %s\n", batch.DescribeLines(pos, pos), err)
+			continue
+		}
+
+		up.totalFailedRows += 1
+	}
+}
+
+// rows skipped by input config are not uploaded to any target, so they are written once
+func (up *Uploader) writeRejectedRows(batch *input.Batch) {
+	for _, rejected := range batch.Rejected {
+		fmt.Printf("[Input] skipped %s\n", rejected.Err)
+
+		if up.FailedRows == nil {
+			continue
+		}
+
+		if err := up.FailedRows.Write(rejected.Row, rejected.Err.Error()); err != nil {
+			fmt.Printf("[Failed Rows] unable to write line %d: %s\n", rejected.Line, err)
+			continue
+		}
+
+		up.totalFailedRows += 1
+	}
+}
+
+func (up *Uploader) closeFailedRows() {
+	if up.FailedRows == nil {
+		return
+	}
+
+	err := up.FailedRows.Close()
+	up.FailedRows = nil
+
+	if err != nil {
+		fmt.Printf("[Failed Rows] unable to write failed rows file: %s\n", err)
+		return
+	}
+
+	if up.totalFailedRows > 0 {
+		fmt.Printf("[Failed Rows] %d rejected rows written to %s\n", up.totalFailedRows, up.Config.FailedRows.Path)
+	}
+}
+
+// record result of a target batch to result file and failed rows, returns total of failed rows and its last error
+func (up *Uploader) recordResult(targetID string, batch *input.Batch, okStatus output.RowStatus, res *processor.Result, batchErr error, failures map[int][]string) (failedRows int, lastErr error) {
+	if batchErr != nil {
+		up.writeOutput(targetID, batch, output.RowStatusFailed, res, batchErr)
+
+		for pos := range batch.Data {
+			addFailure(failures, targetID, batch, pos, batchErr)
+		}
+
+		return len(batch.Data), batchErr
+	}
+
+	// batch is executed as a whole
+	if res.RowErrors == nil {
+		up.writeOutput(targetID, batch, okStatus, res, nil)
+		return 0, nil
+	}
+
+	for pos, rowErr := range res.RowErrors {
+		status := okStatus
+		if rowErr != nil {
+			status = output.RowStatusFailed
+			failedRows += 1
+			lastErr = rowErr
+			addFailure(failures, targetID, batch, pos, rowErr)
+		}
+
+		err := up.Output.WriteRow(targetID, batch.RowIndex(pos)+1, status, res.Values[pos], rowErr)
+		if err != nil {
+			fmt.Printf("[Output] unable to write result of %s: %s\n", batch.DescribeLines(pos, pos), err)
+		}
+	}
+
+	return failedRows, lastErr
+}
+
+// total of rows at the beginning of batch which are already processed, progress is the index of the next
+// row to be processed
+func getProcessedRows(batch *input.Batch, progress int) int {
+	processed := 0
+	for processed < len(batch.Data) && batch.RowIndex(processed) < progress {
+		processed += 1
+	}
+
+	return processed
+}
+
+func addFailure(failures map[int][]string, targetID string, batch *input.Batch, pos int, rowErr error) {
+	index := batch.RowIndex(pos)
+	msg := fmt.Sprintf("[Target ID: %s] %s: %s", targetID, batch.DescribeLines(pos, pos), rowErr)
+	failures[index] = append(failures[index], msg)
 }
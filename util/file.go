@@ -0,0 +1,26 @@
+package util
+
+import (
+	"io"
+	"os"
+)
+
+// copy content of reader to a new temporary file, returns path of the file which must be removed by caller
+func CopyToTempFile(r io.Reader, pattern string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
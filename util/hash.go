@@ -0,0 +1,93 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+const (
+	// files larger than this size are fingerprinted by sampling, so it takes constant time
+	FullHashMaxSize = 64 << 20
+	hashSampleSize  = 1 << 20
+	hashSampleCount = 16
+)
+
+// sha256 of file content in hex format
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sha256 of file size, modification time, and evenly spaced samples of file content, including the first
+// and last bytes. Modification time catches same size edits between samples. Small files are fully hashed
+func FingerprintFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	size := info.Size()
+	if size <= FullHashMaxSize {
+		return HashFile(path)
+	}
+
+	hash := sha256.New()
+	binary.Write(hash, binary.BigEndian, size)
+	binary.Write(hash, binary.BigEndian, info.ModTime().UnixNano())
+
+	sample := make([]byte, hashSampleSize)
+	step := (size - hashSampleSize) / (hashSampleCount - 1)
+	for i := int64(0); i < hashSampleCount; i++ {
+		n, err := f.ReadAt(sample, i*step)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+
+		hash.Write(sample[:n])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sha256 of text in hex format
+func HashString(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// sha256 of path and fingerprint of every file, so modified, renamed, added, or removed file changes
+// the result
+func FingerprintFiles(paths []string) (string, error) {
+	hash := sha256.New()
+	for _, path := range paths {
+		fingerprint, err := FingerprintFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		hash.Write([]byte(path + "\x00" + fingerprint + "\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
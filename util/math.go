@@ -0,0 +1,9 @@
+package util
+
+func MinInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}